@@ -0,0 +1,235 @@
+package toolrun
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// BackendObserver receives the outcome of every call dispatched to a backend.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Performance: called inline after each dispatch; must not block.
+// - Errors: err is the call's ToolError, or nil on success.
+// - Context: calls ended by the caller's context (including lost hedges) are not observed.
+type BackendObserver interface {
+	ObserveBackend(backend toolmodel.ToolBackend, latency time.Duration, err error)
+}
+
+// AdaptiveSelectorConfig configures an AdaptiveSelector.
+type AdaptiveSelectorConfig struct {
+	// Alpha is the EWMA smoothing factor in (0, 1]: the weight of the latest
+	// call in the success rate and latency averages. Defaults to 0.2.
+	Alpha float64 `json:"alpha,omitempty"`
+
+	// Weights are static multipliers of a backend's score, keyed by backend
+	// ("provider:<id>:<tool>"), instance ("mcp:<server>", "provider:<id>",
+	// "local:<name>"), or kind ("mcp", "provider", "local"), most specific
+	// first. Unlisted backends weigh 1; a weight of 0 excludes a backend
+	// unless no other is available.
+	Weights map[string]float64 `json:"weights,omitempty"`
+
+	// Recovery is the half-life over which an idle backend's failure
+	// history fades, so that backends that failed are tried again.
+	// Defaults to one minute.
+	Recovery time.Duration `json:"recovery,omitempty"`
+
+	// IsFailure reports whether a failed call counts against a backend's
+	// success rate. Nil uses DefaultRetryable, so validation errors do not.
+	IsFailure func(err error) bool `json:"-"`
+}
+
+// BackendStats are the statistics an AdaptiveSelector keeps for a backend.
+type BackendStats struct {
+	// Backend is the backend the statistics describe.
+	Backend toolmodel.ToolBackend `json:"backend"`
+
+	// Calls and Failures count the observed calls.
+	Calls    int64 `json:"calls"`
+	Failures int64 `json:"failures"`
+
+	// SuccessRate is the EWMA of call success, from 0 to 1, as of the last
+	// call.
+	SuccessRate float64 `json:"successRate"`
+
+	// Latency is the EWMA of the latency of successful calls, or zero
+	// before the first one.
+	Latency time.Duration `json:"latency"`
+
+	// LastCall is when the last call was observed.
+	LastCall time.Time `json:"lastCall"`
+}
+
+// AdaptiveSelector is a BackendSelector that prefers healthy, fast backends.
+// It learns each backend's success rate and latency from the calls a runner
+// reports to it, so it must be installed both as the selector and as the
+// observer:
+//
+//	sel := toolrun.NewAdaptiveSelector(toolrun.AdaptiveSelectorConfig{})
+//	runner := toolrun.NewRunner(
+//	    toolrun.WithBackendSelector(sel.Select),
+//	    toolrun.WithBackendObserver(sel),
+//	)
+//
+// A backend's score is its weight times the square of its success rate
+// divided by its latency. Backends without observations are scored with the
+// best latency seen among the candidates, so that they get tried; ties keep
+// the toolindex.DefaultBackendSelector order (local > provider > mcp).
+// It is safe for concurrent use.
+type AdaptiveSelector struct {
+	cfg AdaptiveSelectorConfig
+	now func() time.Time
+
+	mu    sync.Mutex
+	stats map[string]*BackendStats
+}
+
+// NewAdaptiveSelector creates an adaptive selector.
+func NewAdaptiveSelector(cfg AdaptiveSelectorConfig) *AdaptiveSelector {
+	if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+		cfg.Alpha = 0.2
+	}
+	if cfg.Recovery <= 0 {
+		cfg.Recovery = time.Minute
+	}
+	return &AdaptiveSelector{
+		cfg:   cfg,
+		now:   time.Now,
+		stats: make(map[string]*BackendStats),
+	}
+}
+
+// Select chooses the backend with the best score. It has the signature of
+// toolindex.BackendSelector.
+func (s *AdaptiveSelector) Select(backends []toolmodel.ToolBackend) toolmodel.ToolBackend {
+	if len(backends) == 0 {
+		return toolmodel.ToolBackend{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	best := time.Duration(0)
+	for _, b := range backends {
+		if st, ok := s.stats[backendKey(b)]; ok && st.Latency > 0 && (best == 0 || st.Latency < best) {
+			best = st.Latency
+		}
+	}
+	if best == 0 {
+		best = time.Millisecond
+	}
+
+	var chosen toolmodel.ToolBackend
+	chosenScore, chosenRank := -1.0, math.MaxInt
+	for _, b := range backends {
+		score := s.weight(b)
+		latency := best
+		if st, ok := s.stats[backendKey(b)]; ok {
+			success := s.recovered(st, now)
+			score *= success * success
+			if st.Latency > 0 {
+				latency = st.Latency
+			}
+		}
+		score /= latency.Seconds()
+		rank := kindRank(b.Kind)
+		if score > chosenScore || (score == chosenScore && rank < chosenRank) {
+			chosen, chosenScore, chosenRank = b, score, rank
+		}
+	}
+	return chosen
+}
+
+// ObserveBackend records the outcome of a call. It implements
+// BackendObserver.
+func (s *AdaptiveSelector) ObserveBackend(backend toolmodel.ToolBackend, latency time.Duration, err error) {
+	failed := err != nil && s.isFailure(err)
+	if err != nil && !failed {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	key := backendKey(backend)
+	st, ok := s.stats[key]
+	if !ok {
+		st = &BackendStats{Backend: backend, SuccessRate: 1}
+		s.stats[key] = st
+	}
+	success := 1.0
+	if failed {
+		success = 0
+		st.Failures++
+	}
+	a := s.cfg.Alpha
+	st.SuccessRate = a*success + (1-a)*s.recovered(st, now)
+	switch {
+	case failed:
+		// Failed calls say nothing about how fast the backend answers.
+	case st.Latency == 0:
+		st.Latency = latency
+	default:
+		st.Latency = time.Duration(a*float64(latency) + (1-a)*float64(st.Latency))
+	}
+	st.Calls++
+	st.LastCall = now
+}
+
+// Stats returns a snapshot of the statistics of every observed backend,
+// keyed by backend ("mcp:<server>", "provider:<id>:<tool>", or
+// "local:<name>").
+func (s *AdaptiveSelector) Stats() map[string]BackendStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]BackendStats, len(s.stats))
+	for key, st := range s.stats {
+		out[key] = *st
+	}
+	return out
+}
+
+// weight returns the static weight of a backend.
+func (s *AdaptiveSelector) weight(b toolmodel.ToolBackend) float64 {
+	for _, key := range []string{backendKey(b), instanceKey(b), string(b.Kind)} {
+		if w, ok := s.cfg.Weights[key]; ok {
+			// Excluded backends still rank above nothing.
+			return max(w, 1e-9)
+		}
+	}
+	return 1
+}
+
+// recovered returns a backend's success rate with its failure history faded
+// by the time since its last call.
+func (s *AdaptiveSelector) recovered(st *BackendStats, now time.Time) float64 {
+	if st.LastCall.IsZero() {
+		return st.SuccessRate
+	}
+	idle := now.Sub(st.LastCall)
+	fade := math.Pow(0.5, float64(idle)/float64(s.cfg.Recovery))
+	return 1 - (1-st.SuccessRate)*fade
+}
+
+func (s *AdaptiveSelector) isFailure(err error) bool {
+	if s.cfg.IsFailure != nil {
+		return s.cfg.IsFailure(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// kindRank orders backend kinds as toolindex.DefaultBackendSelector does.
+func kindRank(kind toolmodel.BackendKind) int {
+	switch kind {
+	case toolmodel.BackendKindLocal:
+		return 0
+	case toolmodel.BackendKindProvider:
+		return 1
+	case toolmodel.BackendKindMCP:
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,160 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+func newTestSelector(cfg AdaptiveSelectorConfig) (*AdaptiveSelector, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	s := NewAdaptiveSelector(cfg)
+	s.now = clock.now
+	return s, clock
+}
+
+func testAdaptiveBackends() []toolmodel.ToolBackend {
+	return []toolmodel.ToolBackend{
+		testMCPBackend("srv"),
+		testProviderBackend("p", "t"),
+		testLocalBackend("t"),
+	}
+}
+
+func execErr() error {
+	b := testLocalBackend("t")
+	return WrapError("t", &b, "execute", fmt.Errorf("%w: %v", ErrExecution, errTest))
+}
+
+func TestAdaptiveSelector_DefaultOrderWithoutStats(t *testing.T) {
+	s, _ := newTestSelector(AdaptiveSelectorConfig{})
+
+	if got := s.Select(testAdaptiveBackends()); got.Kind != toolmodel.BackendKindLocal {
+		t.Errorf("Select() = %s, want local", got.Kind)
+	}
+	if got := s.Select(nil); got.Kind != "" {
+		t.Errorf("Select(nil) = %s, want zero backend", got.Kind)
+	}
+}
+
+func TestAdaptiveSelector_PrefersFastBackend(t *testing.T) {
+	s, _ := newTestSelector(AdaptiveSelectorConfig{})
+	backends := []toolmodel.ToolBackend{testMCPBackend("srv"), testLocalBackend("t")}
+	for range 5 {
+		s.ObserveBackend(backends[1], 100*time.Millisecond, nil)
+		s.ObserveBackend(backends[0], 10*time.Millisecond, nil)
+	}
+
+	if got := s.Select(backends); got.Kind != toolmodel.BackendKindMCP {
+		t.Errorf("Select() = %s, want the faster mcp backend", got.Kind)
+	}
+}
+
+func TestAdaptiveSelector_AvoidsFailingBackend(t *testing.T) {
+	s, clock := newTestSelector(AdaptiveSelectorConfig{Alpha: 0.5, Recovery: time.Minute})
+	backends := testAdaptiveBackends()[1:]
+	s.ObserveBackend(backends[0], 10*time.Millisecond, nil)
+	s.ObserveBackend(backends[1], 5*time.Millisecond, nil)
+	for range 3 {
+		s.ObserveBackend(backends[1], 10*time.Millisecond, execErr())
+	}
+
+	if got := s.Select(backends); got.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("Select() = %s, want the healthy provider backend", got.Kind)
+	}
+	stats := s.Stats()["local:t"]
+	if stats.Calls != 4 || stats.Failures != 3 || stats.SuccessRate >= 0.5 {
+		t.Errorf("stats = %+v, want 4 calls, 3 failures, low success rate", stats)
+	}
+
+	// The failure history fades while the backend is idle.
+	clock.advance(10 * time.Minute)
+	if got := s.Select(backends); got.Kind != toolmodel.BackendKindLocal {
+		t.Errorf("Select() after recovery = %s, want local", got.Kind)
+	}
+}
+
+func TestAdaptiveSelector_IgnoresValidationErrors(t *testing.T) {
+	s, _ := newTestSelector(AdaptiveSelectorConfig{})
+	b := testLocalBackend("t")
+	s.ObserveBackend(b, time.Millisecond, WrapError("t", &b, "validate_input", ErrValidation))
+
+	if stats := s.Stats(); len(stats) != 0 {
+		t.Errorf("Stats() = %v, want no observations", stats)
+	}
+}
+
+func TestAdaptiveSelector_Weights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		want    toolmodel.BackendKind
+	}{
+		{"kind", map[string]float64{"mcp": 10}, toolmodel.BackendKindMCP},
+		{"instance", map[string]float64{"provider:p": 10}, toolmodel.BackendKindProvider},
+		{"backend overrides kind", map[string]float64{"provider": 10, "provider:p:t": 0.1}, toolmodel.BackendKindLocal},
+		{"zero excludes", map[string]float64{"local": 0, "provider": 0}, toolmodel.BackendKindMCP},
+		{"all excluded", map[string]float64{"local": 0, "provider": 0, "mcp": 0}, toolmodel.BackendKindLocal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestSelector(AdaptiveSelectorConfig{Weights: tt.weights})
+			if got := s.Select(testAdaptiveBackends()); got.Kind != tt.want {
+				t.Errorf("Select() = %s, want %s", got.Kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSelector_Run(t *testing.T) {
+	sel := NewAdaptiveSelector(AdaptiveSelectorConfig{Alpha: 1})
+	runner, provider, _ := newFailoverTestRunner(t,
+		WithBackendSelector(sel.Select),
+		WithBackendObserver(sel),
+		WithFailover(FailoverPolicy{Enabled: true}),
+	)
+	provider.CallToolResult = "ok"
+
+	// The first call prefers local, which fails over to the provider.
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil || result.Backend.Kind != toolmodel.BackendKindProvider {
+		t.Fatalf("Run() = %s, %v; want provider result", result.Backend.Kind, err)
+	}
+	stats := sel.Stats()
+	if stats["local:multi"].Failures != 1 || stats["provider:p:multi"].Calls != 1 {
+		t.Fatalf("Stats() = %+v, want one local failure and one provider call", stats)
+	}
+
+	// The next call goes straight to the healthy provider.
+	result, err = runner.Run(context.Background(), "multi", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := backendKinds(result.BackendsTried); len(got) != 1 || got[0] != toolmodel.BackendKindProvider {
+		t.Errorf("BackendsTried = %v, want [provider]", got)
+	}
+}
+
+func TestAdaptiveSelector_SkipsCanceledCalls(t *testing.T) {
+	sel := NewAdaptiveSelector(AdaptiveSelectorConfig{})
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": func(ctx context.Context, _ map[string]any) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	runner.cfg.BackendObserver = sel
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := runner.Run(ctx, "slow", nil); !errors.Is(err, ErrExecution) {
+		t.Fatalf("Run() error = %v, want ErrExecution", err)
+	}
+	if stats := sel.Stats(); len(stats) != 0 {
+		t.Errorf("Stats() = %v, want no observations", stats)
+	}
+}
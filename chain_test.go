@@ -2,6 +2,7 @@ package toolrun
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -508,3 +509,39 @@ func TestRunChain_BackendInStepResult_OnError(t *testing.T) {
 		t.Errorf("results[0].Backend.Local = %#v, want myhandler", results[0].Backend.Local)
 	}
 }
+
+func TestValidateChainShape_SharedByChainEntryPoints(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"t": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+	tests := []struct {
+		name  string
+		steps []ChainStep
+		want  error
+	}{
+		{"error policy", []ChainStep{{ToolID: "t", OnError: &ErrorPolicy{Action: "retry"}}}, ErrInvalidChain},
+		{"reference", []ChainStep{{ToolID: "t", Args: map[string]any{"x": "$.steps[1].result"}}, {ToolID: "t"}}, ErrInvalidReference},
+		{"when", []ChainStep{{ToolID: "t", When: `previous.status = "ok"`}}, ErrInvalidChain},
+		{"compensation", []ChainStep{{ToolID: "t", Compensate: &Compensation{}}}, ErrInvalidToolID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateChainShape(tt.steps); !errors.Is(err, tt.want) {
+				t.Errorf("validateChainShape() error = %v, want %v", err, tt.want)
+			}
+			if _, _, err := runner.RunChain(context.Background(), tt.steps); !errors.Is(err, tt.want) {
+				t.Errorf("RunChain() error = %v, want %v", err, tt.want)
+			}
+			if _, err := NewCompositeTool(testTool("composite"), tt.steps); !errors.Is(err, tt.want) {
+				t.Errorf("NewCompositeTool() error = %v, want %v", err, tt.want)
+			}
+			data, err := json.Marshal(ChainDocument{Version: ChainDocumentVersion, Steps: tt.steps})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseChainDocument(data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseChainDocument() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
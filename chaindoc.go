@@ -0,0 +1,186 @@
+package toolrun
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChainDocumentVersion is the chain document format version understood by
+// ParseChainDocument.
+const ChainDocumentVersion = "toolrun.chain/v1"
+
+// ChainDocument is a chain definition stored as data, for example in a file
+// checked into a repository. Steps use the ChainStep JSON encoding:
+//
+//	{
+//	  "version": "toolrun.chain/v1",
+//	  "name": "triage",
+//	  "steps": [
+//	    {"id": "fetch", "toolId": "github:get_issue", "args": {"number": "$.inputs.number"}},
+//	    {"toolId": "llm:classify", "usePrevious": true}
+//	  ]
+//	}
+//
+// The same document may be written as YAML (see ParseChainDocumentYAML):
+//
+//	version: toolrun.chain/v1
+//	name: triage
+//	steps:
+//	  - id: fetch
+//	    toolId: github:get_issue
+//	    args: {number: $.inputs.number}
+//	  - toolId: llm:classify
+//	    usePrevious: true
+type ChainDocument struct {
+	// Version is the document format version; it must be ChainDocumentVersion.
+	Version string `json:"version"`
+
+	// Name optionally names the chain.
+	Name string `json:"name,omitempty"`
+
+	// Description optionally describes the chain.
+	Description string `json:"description,omitempty"`
+
+	// Timeout optionally bounds the whole chain. RunChainDocument applies it
+	// as with WithChainTimeout.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// Steps are the chain steps, in execution order.
+	Steps []ChainStep `json:"steps"`
+}
+
+// RunChainDocument runs a chain document's steps with RunChain, bounded by
+// the document's Timeout when it is set.
+func (r *DefaultRunner) RunChainDocument(ctx context.Context, doc *ChainDocument) (RunResult, []StepResult, error) {
+	if doc.Timeout > 0 {
+		ctx = WithChainTimeout(ctx, time.Duration(doc.Timeout))
+	}
+	return r.RunChain(ctx, doc.Steps)
+}
+
+// ParseChainDocument decodes and statically checks a JSON chain document.
+// Unknown fields are rejected so that misspelled keys are not silently
+// ignored. Step references and error policies are checked as RunChain would;
+// tools are not resolved (see DefaultRunner.CheckChain).
+func ParseChainDocument(data []byte) (*ChainDocument, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var doc ChainDocument
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: decode chain document: %v", ErrInvalidChain, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data after chain document", ErrInvalidChain)
+	}
+	if doc.Version != ChainDocumentVersion {
+		return nil, fmt.Errorf("%w: unsupported chain document version %q (want %q)",
+			ErrInvalidChain, doc.Version, ChainDocumentVersion)
+	}
+	if len(doc.Steps) == 0 {
+		return nil, fmt.Errorf("%w: chain document has no steps", ErrInvalidChain)
+	}
+	for i, step := range doc.Steps {
+		if step.ToolID == "" {
+			return nil, fmt.Errorf("%w: step %d has no toolId", ErrInvalidChain, i)
+		}
+	}
+	if err := validateChainShape(doc.Steps); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ParseChainDocumentYAML decodes and checks a YAML chain document, with the
+// same keys and checks as ParseChainDocument. Only the first YAML document
+// in data is read; timestamps are kept as strings.
+func ParseChainDocumentYAML(data []byte) (*ChainDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: decode chain document: %v", ErrInvalidChain, err)
+	}
+	v, err := yamlValue(&root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode chain document: %v", ErrInvalidChain, err)
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode chain document: %v", ErrInvalidChain, err)
+	}
+	return ParseChainDocument(data)
+}
+
+// yamlValue converts a YAML node to the value encoding/json would decode
+// from the equivalent JSON.
+func yamlValue(n *yaml.Node) (any, error) {
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+			v, err := yamlValue(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		s := make([]any, len(n.Content))
+		for i, item := range n.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	}
+	if n.ShortTag() == "!!timestamp" {
+		return n.Value, nil
+	}
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// LoadChainDocument reads and parses a chain document from path. Files
+// named *.yaml or *.yml are parsed with ParseChainDocumentYAML, others with
+// ParseChainDocument.
+func LoadChainDocument(path string) (*ChainDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parse := ParseChainDocument
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parse = ParseChainDocumentYAML
+	}
+	doc, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,152 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChainDocument(t *testing.T) {
+	data := []byte(`{
+		"version": "toolrun.chain/v1",
+		"name": "triage",
+		"steps": [
+			{"id": "fetch", "toolId": "github:get_issue", "args": {"number": "$.inputs.number"}},
+			{"toolId": "llm:classify", "usePrevious": true, "onError": {"action": "continue"}}
+		]
+	}`)
+
+	doc, err := ParseChainDocument(data)
+	if err != nil {
+		t.Fatalf("ParseChainDocument() error = %v", err)
+	}
+	if doc.Name != "triage" || len(doc.Steps) != 2 {
+		t.Fatalf("doc = %+v, want triage with 2 steps", doc)
+	}
+	if doc.Steps[0].Args["number"] != "$.inputs.number" {
+		t.Errorf("Steps[0].Args = %v", doc.Steps[0].Args)
+	}
+	if !doc.Steps[1].UsePrevious || doc.Steps[1].OnError == nil || doc.Steps[1].OnError.Action != ErrorActionContinue {
+		t.Errorf("Steps[1] = %+v", doc.Steps[1])
+	}
+}
+
+func TestParseChainDocumentYAML(t *testing.T) {
+	data := []byte(`
+version: toolrun.chain/v1
+name: triage
+timeout: 30s
+steps:
+  - id: fetch
+    toolId: github:get_issue
+    args:
+      number: $.inputs.number
+      since: 2026-01-02
+      limit: 5
+  - toolId: llm:classify
+    usePrevious: true
+    onError: {action: continue}
+`)
+
+	doc, err := ParseChainDocumentYAML(data)
+	if err != nil {
+		t.Fatalf("ParseChainDocumentYAML() error = %v", err)
+	}
+	if doc.Name != "triage" || len(doc.Steps) != 2 || time.Duration(doc.Timeout) != 30*time.Second {
+		t.Fatalf("doc = %+v, want triage with 2 steps and a 30s timeout", doc)
+	}
+	want := map[string]any{"number": "$.inputs.number", "since": "2026-01-02", "limit": float64(5)}
+	if !reflect.DeepEqual(doc.Steps[0].Args, want) {
+		t.Errorf("Steps[0].Args = %#v, want %#v", doc.Steps[0].Args, want)
+	}
+	if !doc.Steps[1].UsePrevious || doc.Steps[1].OnError == nil || doc.Steps[1].OnError.Action != ErrorActionContinue {
+		t.Errorf("Steps[1] = %+v", doc.Steps[1])
+	}
+
+	for name, data := range map[string]string{
+		"unknown field": "version: toolrun.chain/v1\nsteps: [{toolId: a, usePrevous: true}]\n",
+		"syntax":        "version: [\n",
+		"not a mapping": "- toolId: a\n",
+	} {
+		if _, err := ParseChainDocumentYAML([]byte(data)); !errors.Is(err, ErrInvalidChain) {
+			t.Errorf("%s: ParseChainDocumentYAML() error = %v, want ErrInvalidChain", name, err)
+		}
+	}
+}
+
+func TestParseChainDocument_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed", `{"version":`},
+		{"missing version", `{"steps": [{"toolId": "a"}]}`},
+		{"unknown version", `{"version": "toolrun.chain/v9", "steps": [{"toolId": "a"}]}`},
+		{"unknown field", `{"version": "toolrun.chain/v1", "steps": [{"toolId": "a", "usePrevius": true}]}`},
+		{"no steps", `{"version": "toolrun.chain/v1", "steps": []}`},
+		{"missing toolId", `{"version": "toolrun.chain/v1", "steps": [{"id": "x"}]}`},
+		{"bad policy", `{"version": "toolrun.chain/v1", "steps": [{"toolId": "a", "onError": {"action": "retry"}}]}`},
+		{"trailing data", `{"version": "toolrun.chain/v1", "steps": [{"toolId": "a"}]} {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseChainDocument([]byte(tt.data)); !errors.Is(err, ErrInvalidChain) {
+				t.Errorf("ParseChainDocument() error = %v, want ErrInvalidChain", err)
+			}
+		})
+	}
+}
+
+func TestParseChainDocument_InvalidReference(t *testing.T) {
+	data := `{"version": "toolrun.chain/v1", "steps": [{"toolId": "a", "args": {"x": "$.steps.later.result"}}, {"id": "later", "toolId": "b"}]}`
+	if _, err := ParseChainDocument([]byte(data)); !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("ParseChainDocument() error = %v, want ErrInvalidReference", err)
+	}
+}
+
+func TestLoadChainDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chain.json")
+	data := `{"version": "toolrun.chain/v1", "steps": [{"toolId": "a"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := LoadChainDocument(path)
+	if err != nil {
+		t.Fatalf("LoadChainDocument() error = %v", err)
+	}
+	if len(doc.Steps) != 1 || doc.Steps[0].ToolID != "a" {
+		t.Errorf("doc.Steps = %+v", doc.Steps)
+	}
+
+	yamlPath := filepath.Join(t.TempDir(), "chain.yaml")
+	if err := os.WriteFile(yamlPath, []byte("version: toolrun.chain/v1\nsteps:\n  - toolId: b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	doc, err = LoadChainDocument(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadChainDocument(yaml) error = %v", err)
+	}
+	if len(doc.Steps) != 1 || doc.Steps[0].ToolID != "b" {
+		t.Errorf("yaml doc.Steps = %+v", doc.Steps)
+	}
+
+	if _, err := LoadChainDocument(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadChainDocument(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestRunChainDocument_Timeout(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"slow": sleepHandler(time.Second)})
+	doc, err := ParseChainDocument([]byte(`{"version": "toolrun.chain/v1", "timeout": "10ms", "steps": [{"toolId": "slow"}]}`))
+	if err != nil {
+		t.Fatalf("ParseChainDocument() error = %v", err)
+	}
+
+	if _, _, err := runner.RunChainDocument(context.Background(), doc); !errors.Is(err, ErrChainTimeout) {
+		t.Errorf("RunChainDocument() error = %v, want ErrChainTimeout", err)
+	}
+}
@@ -0,0 +1,176 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonwraymond/toolmodel"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RunChainStream executes a chain and streams its events.
+// For each step it emits StreamEventStepStarted, then the step's own events,
+// then StreamEventStepCompleted or StreamEventStepFailed with the StepResult
+// as Data. Steps whose backend supports streaming are run with streaming and
+// their chunk and progress events are forwarded; other steps run as in
+// RunChain. Every event carries the step's StepIndex and the ToolID that
+// produced it. The stream ends with a single StreamEventDone event whose Data
+// is the final RunResult, or a StreamEventError event carrying the chain error.
+//
+// Malformed chains are reported synchronously, before any step runs.
+func (r *DefaultRunner) RunChainStream(ctx context.Context, steps []ChainStep) (<-chan StreamEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.checkChain(steps); err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		sink := &chainSink{ctx: ctx, out: out}
+		final, _, err := r.runChainWithProgress(withChainSink(ctx, sink), steps, nil)
+		if err != nil {
+			sink.send(StreamEvent{Kind: StreamEventError, Data: final, Err: err})
+			return
+		}
+		sink.send(StreamEvent{Kind: StreamEventDone, Data: final})
+	}()
+	return out, nil
+}
+
+// chainSink forwards events from a streaming chain to its consumer.
+type chainSink struct {
+	ctx  context.Context
+	out  chan<- StreamEvent
+	step int
+}
+
+// chainSinkKey is the context key for the active chain sink.
+type chainSinkKey struct{}
+
+// withChainSink returns a context carrying sink. A nil sink masks any sink
+// inherited from an enclosing RunChainStream, so that nested chains run
+// through Run and do not leak events into the outer stream.
+func withChainSink(ctx context.Context, sink *chainSink) context.Context {
+	return context.WithValue(ctx, chainSinkKey{}, sink)
+}
+
+// chainSinkFrom returns the sink attached to ctx, if any.
+func chainSinkFrom(ctx context.Context) *chainSink {
+	sink, _ := ctx.Value(chainSinkKey{}).(*chainSink)
+	return sink
+}
+
+// forStep returns a sink that tags events with step index i.
+func (s *chainSink) forStep(i int) *chainSink {
+	return &chainSink{ctx: s.ctx, out: s.out, step: i}
+}
+
+// emit tags ev with the sink's step index and sends it.
+func (s *chainSink) emit(ev StreamEvent) {
+	step := s.step
+	ev.StepIndex = &step
+	s.send(ev)
+}
+
+// send delivers ev unless the consumer's context is done.
+func (s *chainSink) send(ev StreamEvent) {
+	select {
+	case s.out <- ev:
+	case <-s.ctx.Done():
+	}
+}
+
+// stepDone emits the completion or failure event for a step.
+func (s *chainSink) stepDone(sr StepResult) {
+	ev := StreamEvent{Kind: StreamEventStepCompleted, ToolID: sr.ToolID, Data: sr}
+	if sr.Err != nil {
+		ev.Kind = StreamEventStepFailed
+		ev.Err = sr.Err
+	}
+	s.emit(ev)
+}
+
+// execute runs a tool on behalf of a chain step. Inside RunChainStream it
+// streams the tool and forwards its events; otherwise it is Run.
+func (r *DefaultRunner) execute(ctx context.Context, toolID string, args map[string]any) (RunResult, error) {
+	sink := chainSinkFrom(ctx)
+	if sink == nil {
+		return r.Run(ctx, toolID, args)
+	}
+	return r.runStreamed(ctx, toolID, args, sink)
+}
+
+// runStreamed runs a tool with streaming, forwarding its chunk and progress
+// events to sink, and builds the RunResult from its done event. Backend calls
+// are made as in Run, with limits, timeouts, circuit breakers, retries, and
+// failover; backends that do not stream are dispatched without streaming.
+func (r *DefaultRunner) runStreamed(ctx context.Context, toolID string, args map[string]any, sink *chainSink) (RunResult, error) {
+	call, backends, err := r.resolveCall(ctx, toolID, args)
+	if err != nil {
+		return r.intercept(ctx, unresolvedCall(toolID, args), failedRun(err))
+	}
+	return r.intercept(ctx, call, func(ctx context.Context, call *Call) (RunResult, error) {
+		opts := dispatchOptions{sink: sink, secrets: r.newSecretCache()}
+		adm, err := r.admit(ctx, call, backends, opts.secrets)
+		if err != nil {
+			return RunResult{}, err
+		}
+		result, err := r.runPrepared(ctx, call.ToolID, call.Tool, adm.backends, adm.args, nil, opts)
+		result.Coercions = adm.coercions
+		return result, err
+	})
+}
+
+// dispatchStreamed dispatches a call to backend with streaming, forwarding
+// its chunk and progress events to sink with secret values redacted, and
+// returns the result carried by its done event. Backends that do not stream
+// are dispatched with dispatch.
+func (r *DefaultRunner) dispatchStreamed(ctx context.Context, toolID string, tool toolmodel.Tool, backend toolmodel.ToolBackend, args map[string]any, red *redactor, sink *chainSink) (*dispatchResult, error) {
+	raw, err := r.dispatchStream(ctx, tool, backend, args)
+	if errors.Is(err, ErrStreamNotSupported) || (err == nil && raw == nil) {
+		return r.dispatch(ctx, tool, backend, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case ev, ok := <-raw:
+			if !ok {
+				return nil, errors.New("stream ended without a done event")
+			}
+			switch ev.Kind {
+			case StreamEventDone:
+				return streamDispatchResult(ev.Data), nil
+			case StreamEventError:
+				if ev.Err == nil {
+					return nil, errors.New("stream error")
+				}
+				return nil, ev.Err
+			default:
+				if ev.ToolID == "" {
+					ev.ToolID = toolID
+				}
+				sink.emit(red.event(ev))
+			}
+		}
+	}
+}
+
+// streamDispatchResult converts the Data of a done event into a dispatch
+// result: MCP results are normalized as in Run, other values are structured.
+func streamDispatchResult(data any) *dispatchResult {
+	if res, ok := data.(*mcp.CallToolResult); ok {
+		return &dispatchResult{mcpResult: res}
+	}
+	return &dispatchResult{structured: data}
+}
+
+// Ensure DefaultRunner implements ChainStreamRunner.
+var _ ChainStreamRunner = (*DefaultRunner)(nil)
@@ -0,0 +1,215 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// collectEvents drains a stream.
+func collectEvents(ch <-chan StreamEvent) []StreamEvent {
+	var events []StreamEvent
+	for ev := range ch {
+		events = append(events, ev)
+	}
+	return events
+}
+
+// stepIndex returns ev's step index, or -1 when it has none.
+func stepIndex(ev StreamEvent) int {
+	if ev.StepIndex == nil {
+		return -1
+	}
+	return *ev.StepIndex
+}
+
+// newStreamChainRunner registers a streaming provider tool "gen" and a local
+// tool "upper" that echoes its previous arg.
+func newStreamChainRunner(t *testing.T, stream chan StreamEvent) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("gen"), testProviderBackend("p", "gen"))
+	mustRegisterTool(t, idx, testTool("upper"), testLocalBackend("upper"))
+	provider := newMockProviderExecutor()
+	provider.CallToolStreamChan = stream
+	localReg := newMockLocalRegistry()
+	localReg.Register("upper", func(_ context.Context, args map[string]any) (any, error) {
+		return map[string]any{"got": args["previous"]}, nil
+	})
+	return NewRunner(
+		WithIndex(idx),
+		WithProviderExecutor(provider),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+	)
+}
+
+func TestRunChainStream_Events(t *testing.T) {
+	stream := make(chan StreamEvent, 3)
+	stream <- StreamEvent{Kind: StreamEventChunk, Data: "he"}
+	stream <- StreamEvent{Kind: StreamEventProgress, Data: 0.5}
+	stream <- StreamEvent{Kind: StreamEventDone, Data: "hello"}
+	close(stream)
+	runner := newStreamChainRunner(t, stream)
+
+	ch, err := runner.RunChainStream(context.Background(), []ChainStep{
+		{ToolID: "gen"},
+		{ToolID: "upper", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	events := collectEvents(ch)
+
+	want := []struct {
+		kind   StreamEventKind
+		step   int
+		toolID string
+	}{
+		{StreamEventStepStarted, 0, "gen"},
+		{StreamEventChunk, 0, "gen"},
+		{StreamEventProgress, 0, "gen"},
+		{StreamEventStepCompleted, 0, "gen"},
+		{StreamEventStepStarted, 1, "upper"},
+		{StreamEventStepCompleted, 1, "upper"},
+		{StreamEventDone, -1, ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i, w := range want {
+		ev := events[i]
+		if ev.Kind != w.kind || stepIndex(ev) != w.step || ev.ToolID != w.toolID {
+			t.Errorf("events[%d] = {%s step=%d tool=%q}, want {%s step=%d tool=%q}",
+				i, ev.Kind, stepIndex(ev), ev.ToolID, w.kind, w.step, w.toolID)
+		}
+	}
+
+	sr, ok := events[3].Data.(StepResult)
+	if !ok || sr.Result.Structured != "hello" {
+		t.Errorf("step 0 completed Data = %+v, want StepResult with hello", events[3].Data)
+	}
+	final, ok := events[6].Data.(RunResult)
+	if !ok {
+		t.Fatalf("done Data = %T, want RunResult", events[6].Data)
+	}
+	if got := final.Structured.(map[string]any)["got"]; got != "hello" {
+		t.Errorf("final.Structured = %v, want got=hello", final.Structured)
+	}
+}
+
+func TestRunChainStream_StepFailure(t *testing.T) {
+	stream := make(chan StreamEvent, 1)
+	stream <- StreamEvent{Kind: StreamEventError, Err: errTest}
+	close(stream)
+	runner := newStreamChainRunner(t, stream)
+
+	ch, err := runner.RunChainStream(context.Background(), []ChainStep{
+		{ToolID: "gen"},
+		{ToolID: "upper"},
+	})
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	events := collectEvents(ch)
+	if len(events) != 3 {
+		t.Fatalf("got %d events %+v, want 3", len(events), events)
+	}
+	if events[1].Kind != StreamEventStepFailed || !errors.Is(events[1].Err, ErrExecution) {
+		t.Errorf("events[1] = %+v, want step_failed with ErrExecution", events[1])
+	}
+	last := events[2]
+	if last.Kind != StreamEventError || !errors.Is(last.Err, ErrExecution) {
+		t.Errorf("last event = %+v, want error with ErrExecution", last)
+	}
+}
+
+func TestRunChainStream_InvalidChain(t *testing.T) {
+	runner := newStreamChainRunner(t, nil)
+	_, err := runner.RunChainStream(context.Background(), []ChainStep{
+		{ToolID: "gen", Args: map[string]any{"x": "$.steps.nope.result"}},
+	})
+	if !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("RunChainStream() error = %v, want ErrInvalidReference", err)
+	}
+}
+
+func TestRunChainStream_Empty(t *testing.T) {
+	ch, err := NewRunner().RunChainStream(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	events := collectEvents(ch)
+	if len(events) != 1 || events[0].Kind != StreamEventDone {
+		t.Errorf("events = %+v, want a single done event", events)
+	}
+}
+
+func TestRunChain_DoesNotStream(t *testing.T) {
+	// Without RunChainStream the provider's non-streaming path is used.
+	runner := newStreamChainRunner(t, nil)
+	provider := runner.cfg.Provider.(*mockProviderExecutor)
+	provider.CallToolResult = "plain"
+
+	final, _, err := runner.RunChain(context.Background(), []ChainStep{{ToolID: "gen"}})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if final.Structured != "plain" {
+		t.Errorf("final.Structured = %v, want plain", final.Structured)
+	}
+}
+
+func TestRunChainStream_HooksRunOncePerBackendCall(t *testing.T) {
+	runner := newStreamChainRunner(t, nil)
+	calls := 0
+	runner.cfg.Hooks.BeforeDispatch = func(_ context.Context, call Call) (map[string]any, error) {
+		calls++
+		return call.Args, nil
+	}
+
+	// "upper" is local and does not stream, so it is dispatched without
+	// streaming after the hook has run once.
+	events := collectEvents(mustRunChainStream(t, runner, []ChainStep{{ToolID: "upper"}}))
+	if last := events[len(events)-1]; last.Kind != StreamEventDone {
+		t.Fatalf("last event = %+v, want done", last)
+	}
+	if calls != 1 {
+		t.Errorf("BeforeDispatch ran %d times, want 1", calls)
+	}
+}
+
+func TestRunChainStream_CircuitBreakerAndTimeout(t *testing.T) {
+	t.Run("breaker", func(t *testing.T) {
+		stream := make(chan StreamEvent, 1)
+		stream <- StreamEvent{Kind: StreamEventError, Err: errTest}
+		close(stream)
+		runner := newStreamChainRunner(t, stream)
+		runner.cfg.CircuitBreakers = NewCircuitBreakers(CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+
+		collectEvents(mustRunChainStream(t, runner, []ChainStep{{ToolID: "gen"}}))
+		if state := runner.cfg.CircuitBreakers.State(testProviderBackend("p", "gen")); state != CircuitOpen {
+			t.Errorf("circuit = %s after a failed streamed step, want open", state)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		runner := newStreamChainRunner(t, make(chan StreamEvent))
+		runner.cfg.Timeouts = TimeoutConfig{Provider: 10 * time.Millisecond}
+
+		events := collectEvents(mustRunChainStream(t, runner, []ChainStep{{ToolID: "gen"}}))
+		if last := events[len(events)-1]; !errors.Is(last.Err, ErrCallTimeout) {
+			t.Errorf("last event = %+v, want ErrCallTimeout", last)
+		}
+	})
+}
+
+func mustRunChainStream(t *testing.T, runner *DefaultRunner, steps []ChainStep) <-chan StreamEvent {
+	t.Helper()
+	ch, err := runner.RunChainStream(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	return ch
+}
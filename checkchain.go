@@ -0,0 +1,293 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// CheckChain resolves every tool a chain uses and checks the chain against
+// the tools' schemas without executing anything:
+//   - references and error policies must be valid, as RunChain requires;
+//   - every step, fallback, and compensation tool must resolve;
+//   - static args (values without references or secret references) must
+//     satisfy the step tool's InputSchema after the configured schema
+//     defaults and coercions, and each required arg must be supplied
+//     statically, by a schema default, or by a reference, secret,
+//     UsePrevious, Project, or ForEach;
+//   - when UsePrevious is set, the previous step's OutputSchema must be
+//     compatible with the "previous" property of the step's InputSchema.
+//
+// Schema compatibility is conservative: only definite mismatches are
+// reported. All problems are returned together, joined with errors.Join.
+func (r *DefaultRunner) CheckChain(ctx context.Context, steps []ChainStep) error {
+	_, errs, err := r.planChain(ctx, steps)
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// stepError labels err with the step it belongs to.
+func stepError(i int, step ChainStep, err error) error {
+	if step.ID != "" {
+		return fmt.Errorf("step %q: %w", step.ID, err)
+	}
+	return fmt.Errorf("step %d: %w", i, err)
+}
+
+// auxToolIDs returns the fallback and compensation tools a step may call.
+func auxToolIDs(step ChainStep) []string {
+	var ids []string
+	if step.OnError != nil && step.OnError.Action == ErrorActionFallback && step.OnError.FallbackToolID != "" {
+		ids = append(ids, step.OnError.FallbackToolID)
+	}
+	if step.Compensate != nil && step.Compensate.ToolID != "" {
+		ids = append(ids, step.Compensate.ToolID)
+	}
+	return ids
+}
+
+// checkStaticArgs validates the statically known args of a step against the
+// tool's InputSchema, after the schema defaults and coercions a call would
+// apply. Args that are filled at run time are only checked for presence.
+func (r *DefaultRunner) checkStaticArgs(step ChainStep, tool *toolmodel.Tool) error {
+	schema, ok := schemaObject(tool.InputSchema)
+	if !ok {
+		return nil
+	}
+	static, dynamic := splitStaticArgs(step)
+	if r.cfg.ApplySchemaDefaults {
+		static = applySchemaDefaults(tool.InputSchema, static)
+		// Defaults of args filled at run time are replaced by their values.
+		for name := range dynamic {
+			delete(static, name)
+		}
+	}
+	if r.cfg.Coercion.Enabled {
+		static, _ = coerceArgs(tool.InputSchema, static, r.cfg.Coercion.Lenient)
+	}
+
+	var errs []error
+	var required []any
+	for _, name := range schemaStrings(schema["required"]) {
+		switch {
+		case hasKey(static, name):
+			required = append(required, name)
+		case dynamic[name]:
+		default:
+			errs = append(errs, fmt.Errorf("%w: missing required arg %q", ErrValidation, name))
+		}
+	}
+	if schema["additionalProperties"] == false {
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(dynamic)) {
+			if _, declared := props[name]; !declared {
+				errs = append(errs, fmt.Errorf("%w: arg %q is not allowed by the input schema", ErrValidation, name))
+			}
+		}
+	}
+
+	// Validate static args with only the required args that are present.
+	staticSchema := make(map[string]any, len(schema))
+	for k, v := range schema {
+		staticSchema[k] = v
+	}
+	if len(required) > 0 {
+		staticSchema["required"] = required
+	} else {
+		delete(staticSchema, "required")
+	}
+	if err := r.cfg.Validator.Validate(staticSchema, static); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrValidation, err))
+	}
+	return errors.Join(errs...)
+}
+
+// splitStaticArgs separates the args whose values are known before the chain
+// runs from those filled at run time by references, secrets, UsePrevious,
+// Project, or ForEach.
+func splitStaticArgs(step ChainStep) (map[string]any, map[string]bool) {
+	static := make(map[string]any, len(step.Args))
+	dynamic := make(map[string]bool)
+	for k, v := range step.Args {
+		if containsRef(v) {
+			dynamic[k] = true
+		} else {
+			// Without references, resolving only unescapes literals.
+			static[k], _ = resolveRefs(v, nil)
+		}
+	}
+	if step.UsePrevious {
+		delete(static, "previous")
+		dynamic["previous"] = true
+	}
+	for name := range step.Project {
+		delete(static, name)
+		dynamic[name] = true
+	}
+	if step.ForEach != "" {
+		itemArg := step.ItemArg
+		if itemArg == "" {
+			itemArg = defaultItemArg
+		}
+		delete(static, itemArg)
+		dynamic[itemArg] = true
+	}
+	return static, dynamic
+}
+
+// containsRef reports whether v holds a reference or a secret reference
+// anywhere within it.
+func containsRef(v any) bool {
+	if _, ok := secretRef(v); ok {
+		return true
+	}
+	switch val := v.(type) {
+	case string:
+		_, ok := refExpr(val)
+		return ok
+	case map[string]any:
+		for _, item := range val {
+			if containsRef(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if containsRef(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkPreviousCompat checks that the value a producer step injects at
+// args["previous"] fits the consumer's schema for that arg.
+func checkPreviousCompat(producer ChainStep, out, in *toolmodel.Tool) error {
+	inSchema, ok := schemaObject(in.InputSchema)
+	if !ok {
+		return nil
+	}
+	props, _ := inSchema["properties"].(map[string]any)
+	want, ok := props["previous"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	got, ok := schemaObject(out.OutputSchema)
+	if !ok {
+		return nil
+	}
+	if producer.ForEach != "" {
+		got = map[string]any{"type": "array", "items": got}
+	}
+	if problems := schemaCompat(got, want, "previous"); len(problems) > 0 {
+		errs := make([]error, len(problems))
+		for i, p := range problems {
+			errs[i] = fmt.Errorf("%w: %s", ErrValidation, p)
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// schemaCompat lists definite incompatibilities between a schema describing
+// produced values (out) and one describing accepted values (in). Constraints
+// that either side leaves open are assumed compatible.
+func schemaCompat(out, in map[string]any, path string) []string {
+	var problems []string
+
+	outTypes, inTypes := schemaTypes(out), schemaTypes(in)
+	if len(outTypes) > 0 && len(inTypes) > 0 {
+		for _, t := range outTypes {
+			if !slices.Contains(inTypes, t) && !(t == "integer" && slices.Contains(inTypes, "number")) {
+				problems = append(problems, fmt.Sprintf("%s: output type %q is not accepted (want %v)", path, t, inTypes))
+			}
+		}
+		if len(problems) > 0 {
+			return problems
+		}
+	}
+
+	outProps, outHasProps := out["properties"].(map[string]any)
+	inProps, _ := in["properties"].(map[string]any)
+	outRequired := schemaStrings(out["required"])
+	if outHasProps || len(outRequired) > 0 {
+		for _, name := range schemaStrings(in["required"]) {
+			if !slices.Contains(outRequired, name) {
+				problems = append(problems, fmt.Sprintf("%s: required property %q is not guaranteed by the output", path, name))
+			}
+		}
+	}
+	if in["additionalProperties"] == false {
+		for _, name := range slices.Sorted(maps.Keys(outProps)) {
+			if _, ok := inProps[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: output property %q is not allowed", path, name))
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(inProps)) {
+		inProp := inProps[name]
+		outProp, ok := outProps[name].(map[string]any)
+		inMap, ok2 := inProp.(map[string]any)
+		if ok && ok2 {
+			problems = append(problems, schemaCompat(outProp, inMap, path+"."+name)...)
+		}
+	}
+
+	outItems, ok := out["items"].(map[string]any)
+	inItems, ok2 := in["items"].(map[string]any)
+	if ok && ok2 {
+		problems = append(problems, schemaCompat(outItems, inItems, path+"[]")...)
+	}
+	return problems
+}
+
+// schemaObject returns a schema in generic JSON form, or false for nil,
+// boolean, and unencodable schemas.
+func schemaObject(schema any) (map[string]any, bool) {
+	if schema == nil {
+		return nil, false
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, false
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, false
+	}
+	return m, m != nil
+}
+
+// schemaTypes returns the "type" keyword of a schema as a list.
+func schemaTypes(schema map[string]any) []string {
+	if t, ok := schema["type"].(string); ok {
+		return []string{t}
+	}
+	return schemaStrings(schema["type"])
+}
+
+// schemaStrings returns the strings in a JSON array value.
+func schemaStrings(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// hasKey reports whether m has key k.
+func hasKey(m map[string]any, k string) bool {
+	_, ok := m[k]
+	return ok
+}
@@ -0,0 +1,248 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newSchemaTestRunner registers tools with the given input and output schemas.
+func newSchemaTestRunner(t *testing.T, tools map[string][2]any) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	for name, schemas := range tools {
+		tool := toolmodel.Tool{Tool: mcp.Tool{Name: name, InputSchema: schemas[0], OutputSchema: schemas[1]}}
+		mustRegisterTool(t, idx, tool, testLocalBackend(name))
+	}
+	return NewRunner(WithIndex(idx))
+}
+
+func TestCheckChain_Valid(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"fetch": {
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"id": map[string]any{"type": "integer"}, "repo": map[string]any{"type": "string"}},
+				"required":   []any{"id", "repo"},
+			},
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"title": map[string]any{"type": "string"}},
+				"required":   []any{"title"},
+			},
+		},
+		"classify": {
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{"previous": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"title": map[string]any{"type": "string"}},
+					"required":   []any{"title"},
+				}},
+				"required": []any{"previous"},
+			},
+			nil,
+		},
+	})
+
+	steps := []ChainStep{
+		{ToolID: "fetch", Args: map[string]any{"id": "$.inputs.id", "repo": "toolrun"}},
+		{ToolID: "classify", UsePrevious: true},
+	}
+	if err := runner.CheckChain(context.Background(), steps); err != nil {
+		t.Errorf("CheckChain() error = %v", err)
+	}
+}
+
+func TestCheckChain_ReportsAllProblems(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"fetch": {
+			map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"id": map[string]any{"type": "integer"}},
+				"required":             []any{"id"},
+				"additionalProperties": false,
+			},
+			map[string]any{"type": "string"},
+		},
+		"classify": {
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"previous": map[string]any{"type": "object"}},
+			},
+			nil,
+		},
+	})
+
+	steps := []ChainStep{
+		{ID: "fetch", ToolID: "fetch", Args: map[string]any{"id": "seven"}},
+		{ID: "classify", ToolID: "classify", UsePrevious: true},
+		{ID: "missing", ToolID: "nope"},
+	}
+	err := runner.CheckChain(context.Background(), steps)
+	if err == nil {
+		t.Fatal("CheckChain() error = nil, want problems")
+	}
+	if !errors.Is(err, ErrValidation) || !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("CheckChain() error = %v, want ErrValidation and ErrToolNotFound", err)
+	}
+	for _, want := range []string{`step "fetch"`, `step "classify"`, `step "missing"`, `output type "string"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckChain() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestCheckChain_RequiredArgs(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"t": {
+			map[string]any{
+				"type":     "object",
+				"required": []any{"a", "item"},
+			},
+			nil,
+		},
+	})
+
+	tests := []struct {
+		name    string
+		step    ChainStep
+		wantErr bool
+	}{
+		{"missing", ChainStep{ToolID: "t", Args: map[string]any{"a": 1}}, true},
+		{"static and dynamic", ChainStep{ToolID: "t", Args: map[string]any{"a": 1, "item": "$.inputs.x"}}, false},
+		{"for each item", ChainStep{ToolID: "t", Args: map[string]any{"a": 1}, ForEach: "inputs.list"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runner.CheckChain(context.Background(), []ChainStep{tt.step})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckChain_SecretArgs(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"t": {
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"token": map[string]any{"type": "string"}},
+				"required":   []any{"token"},
+			},
+			nil,
+		},
+	})
+	steps := []ChainStep{{ToolID: "t", Args: map[string]any{"token": map[string]any{"$secret": "github/token"}}}}
+
+	if err := runner.CheckChain(context.Background(), steps); err != nil {
+		t.Errorf("CheckChain() error = %v, want secret args treated as dynamic", err)
+	}
+	plan, err := runner.PlanChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	if !plan.Valid || !hasKey(plan.Steps[0].DynamicArgs, "token") {
+		t.Errorf("plan = %+v, want valid with token dynamic", plan)
+	}
+}
+
+func TestCheckChain_Coercion(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"t": {
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"limit": map[string]any{"type": "integer"}},
+			},
+			nil,
+		},
+	})
+	steps := []ChainStep{{ToolID: "t", Args: map[string]any{"limit": "5"}}}
+
+	if err := runner.CheckChain(context.Background(), steps); !errors.Is(err, ErrValidation) {
+		t.Errorf("CheckChain() error = %v, want ErrValidation without coercion", err)
+	}
+	runner.cfg.Coercion = CoercionPolicy{Enabled: true}
+	if err := runner.CheckChain(context.Background(), steps); err != nil {
+		t.Errorf("CheckChain() error = %v, want the arg coerced", err)
+	}
+}
+
+func TestCheckChain_SchemaDefaults(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"t": {
+			map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"limit": map[string]any{"type": "integer", "default": 10}},
+				"required":   []any{"limit"},
+			},
+			nil,
+		},
+	})
+	steps := []ChainStep{{ToolID: "t"}}
+
+	if err := runner.CheckChain(context.Background(), steps); !errors.Is(err, ErrValidation) {
+		t.Errorf("CheckChain() error = %v, want a missing required arg", err)
+	}
+	runner.cfg.ApplySchemaDefaults = true
+	if err := runner.CheckChain(context.Background(), steps); err != nil {
+		t.Errorf("CheckChain() error = %v, want the default applied", err)
+	}
+}
+
+func TestCheckChain_ResolvesAuxiliaryTools(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{"t": {map[string]any{"type": "object"}, nil}})
+
+	steps := []ChainStep{{
+		ToolID:     "t",
+		OnError:    &ErrorPolicy{Action: ErrorActionFallback, FallbackToolID: "nofallback"},
+		Compensate: &Compensation{ToolID: "noundo"},
+	}}
+	err := runner.CheckChain(context.Background(), steps)
+	if !errors.Is(err, ErrToolNotFound) {
+		t.Fatalf("CheckChain() error = %v, want ErrToolNotFound", err)
+	}
+	for _, want := range []string{"nofallback", "noundo"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckChain() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestSchemaCompat(t *testing.T) {
+	obj := func(props map[string]any, required ...any) map[string]any {
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	str := map[string]any{"type": "string"}
+
+	tests := []struct {
+		name    string
+		out, in map[string]any
+		want    int
+	}{
+		{"integer into number", map[string]any{"type": "integer"}, map[string]any{"type": "number"}, 0},
+		{"type mismatch", str, map[string]any{"type": "object"}, 1},
+		{"open output", map[string]any{}, obj(nil, "x"), 0},
+		{"required not guaranteed", obj(map[string]any{"x": str}), obj(map[string]any{"x": str}, "x"), 1},
+		{"nested mismatch", obj(map[string]any{"x": str}, "x"), obj(map[string]any{"x": map[string]any{"type": "integer"}}), 1},
+		{"array items", map[string]any{"type": "array", "items": str}, map[string]any{"type": "array", "items": map[string]any{"type": "boolean"}}, 1},
+		{
+			"closed input",
+			obj(map[string]any{"x": str, "y": str}),
+			map[string]any{"type": "object", "properties": map[string]any{"x": str}, "additionalProperties": false},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schemaCompat(tt.out, tt.in, "previous"); len(got) != tt.want {
+				t.Errorf("schemaCompat() = %v, want %d problems", got, tt.want)
+			}
+		})
+	}
+}
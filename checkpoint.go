@@ -0,0 +1,268 @@
+package toolrun
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the persisted state of a chain run.
+// Results holds one StepResult per completed step, in step order; a step is
+// completed when it succeeded, was skipped, or its failure was handled by a
+// continue or fallback error policy. StepResult.Err is not serialized, so
+// stores that encode checkpoints as JSON drop it.
+type Checkpoint struct {
+	// RunID identifies the run.
+	RunID string `json:"runId"`
+
+	// Steps is the chain being run.
+	Steps []ChainStep `json:"steps"`
+
+	// Inputs are the chain inputs the run started with.
+	Inputs map[string]any `json:"inputs,omitempty"`
+
+	// Results are the completed steps, in order.
+	Results []StepResult `json:"results,omitempty"`
+
+	// Deadline is when the run's chain timeout expires; zero when it has
+	// none. ResumeChain keeps to it, so that a resumed run aborts with
+	// ErrChainTimeout once it passes, unless the resume sets a new chain
+	// timeout.
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// Done is true once every step has completed.
+	Done bool `json:"done,omitempty"`
+
+	// UpdatedAt is when the checkpoint was last saved.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CheckpointStore persists chain checkpoints.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Ownership: Save must not retain the checkpoint's slices, and Load must
+//   return fresh ones; step args and results are treated as read-only.
+// - Errors: Load returns ErrCheckpointNotFound for unknown run IDs.
+type CheckpointStore interface {
+	// Save stores cp, replacing any checkpoint with the same RunID.
+	Save(ctx context.Context, cp Checkpoint) error
+
+	// Load returns the checkpoint for runID.
+	Load(ctx context.Context, runID string) (Checkpoint, error)
+
+	// Delete removes the checkpoint for runID. Deleting an unknown run is not an error.
+	Delete(ctx context.Context, runID string) error
+}
+
+// runIDKey is the context key for the chain run ID.
+type runIDKey struct{}
+
+// WithRunID returns a context carrying the run ID for chains run with it.
+// When Config.CheckpointStore is set, RunChain checkpoints each completed
+// step under this ID so that ResumeChain can continue the run later.
+// Chains run without a run ID are not checkpointed.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// chainRunID returns the run ID attached with WithRunID, if any.
+func chainRunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// NewRunID returns a random run ID suitable for WithRunID.
+func NewRunID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ResumeChain continues a checkpointed chain from its first incomplete step.
+// Completed steps are not run again: their recorded results are returned as
+// the leading StepResults and feed previous and step references as if they
+// had just run. The chain inputs recorded with the checkpoint are used;
+// inputs attached to ctx are ignored. The run keeps to the deadline recorded
+// with the checkpoint, unless ctx carries a chain timeout (see
+// WithChainTimeout), which replaces it from the time of the resume. Resuming
+// a finished run returns its recorded results without running anything.
+func (r *DefaultRunner) ResumeChain(ctx context.Context, runID string) (RunResult, []StepResult, error) {
+	if r.cfg.CheckpointStore == nil {
+		return RunResult{}, nil, fmt.Errorf("%w: no checkpoint store configured", ErrCheckpointNotFound)
+	}
+	cp, err := r.cfg.CheckpointStore.Load(ctx, runID)
+	if err != nil {
+		return RunResult{}, nil, err
+	}
+	if len(cp.Results) > len(cp.Steps) {
+		return RunResult{}, nil, fmt.Errorf("%w: checkpoint %q has %d results for %d steps",
+			ErrInvalidChain, runID, len(cp.Results), len(cp.Steps))
+	}
+	if err := r.checkChain(cp.Steps); err != nil {
+		return RunResult{}, nil, err
+	}
+	// A chain timeout on ctx starts a new budget for the resumed run.
+	if deadline := chainDeadline(ctx); !deadline.IsZero() {
+		cp.Deadline = deadline
+	}
+	return r.continueChain(withChainSink(ctx, nil), cp.Steps, cp.Results, cp.Inputs, &cp, nil)
+}
+
+// saveCheckpoint stamps and stores cp.
+func (r *DefaultRunner) saveCheckpoint(ctx context.Context, cp *Checkpoint) error {
+	cp.UpdatedAt = time.Now()
+	if err := r.cfg.CheckpointStore.Save(ctx, *cp); err != nil {
+		return fmt.Errorf("checkpoint %q: %w", cp.RunID, err)
+	}
+	return nil
+}
+
+// copyCheckpoint returns a copy of cp that shares no slices with it.
+// Maps inside steps and results are shared; they are never modified.
+func copyCheckpoint(cp Checkpoint) Checkpoint {
+	cp.Steps = append([]ChainStep(nil), cp.Steps...)
+	cp.Results = append([]StepResult(nil), cp.Results...)
+	return cp
+}
+
+// MemoryCheckpointStore is an in-memory CheckpointStore.
+// Checkpoints do not survive the process.
+type MemoryCheckpointStore struct {
+	mu  sync.RWMutex
+	cps map[string]Checkpoint
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{cps: make(map[string]Checkpoint)}
+}
+
+// Save implements CheckpointStore.
+func (s *MemoryCheckpointStore) Save(_ context.Context, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cps[cp.RunID] = copyCheckpoint(cp)
+	return nil
+}
+
+// Load implements CheckpointStore.
+func (s *MemoryCheckpointStore) Load(_ context.Context, runID string) (Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cp, ok := s.cps[runID]
+	if !ok {
+		return Checkpoint{}, fmt.Errorf("%w: %q", ErrCheckpointNotFound, runID)
+	}
+	return copyCheckpoint(cp), nil
+}
+
+// Delete implements CheckpointStore.
+func (s *MemoryCheckpointStore) Delete(_ context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cps, runID)
+	return nil
+}
+
+// FileCheckpointStore is a CheckpointStore that keeps one JSON file per run
+// in a directory. Files are replaced atomically, so a crash mid-save leaves
+// the previous checkpoint intact.
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCheckpointStore creates a file-based checkpoint store rooted at dir.
+// The directory is created on first save if it does not exist.
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{dir: dir}
+}
+
+// path returns the file for runID, rejecting IDs that would escape the directory.
+func (s *FileCheckpointStore) path(runID string) (string, error) {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\`) {
+		return "", fmt.Errorf("invalid run id %q", runID)
+	}
+	return filepath.Join(s.dir, runID+".json"), nil
+}
+
+// Save implements CheckpointStore.
+func (s *FileCheckpointStore) Save(_ context.Context, cp Checkpoint) error {
+	path, err := s.path(cp.RunID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, cp.RunID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load implements CheckpointStore.
+func (s *FileCheckpointStore) Load(_ context.Context, runID string) (Checkpoint, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Checkpoint{}, fmt.Errorf("%w: %q", ErrCheckpointNotFound, runID)
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return Checkpoint{}, fmt.Errorf("checkpoint %q: %w", runID, err)
+	}
+	return cp, nil
+}
+
+// Delete implements CheckpointStore.
+func (s *FileCheckpointStore) Delete(_ context.Context, runID string) error {
+	path, err := s.path(runID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Ensure the built-in stores implement CheckpointStore.
+var (
+	_ CheckpointStore = (*MemoryCheckpointStore)(nil)
+	_ CheckpointStore = (*FileCheckpointStore)(nil)
+)
+
+// Ensure DefaultRunner implements ResumableRunner.
+var _ ResumableRunner = (*DefaultRunner)(nil)
@@ -0,0 +1,216 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyChain returns a runner whose "flaky" tool fails until healed, plus
+// counters for how often the expensive first step ran.
+func flakyChain(t *testing.T, store CheckpointStore) (*DefaultRunner, *atomic.Bool, *atomic.Int32) {
+	t.Helper()
+	var healed atomic.Bool
+	var expensiveCalls atomic.Int32
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	handlers := map[string]LocalHandler{
+		"expensive": func(_ context.Context, args map[string]any) (any, error) {
+			expensiveCalls.Add(1)
+			return map[string]any{"n": args["n"]}, nil
+		},
+		"flaky": func(_ context.Context, args map[string]any) (any, error) {
+			if !healed.Load() {
+				return nil, errTest
+			}
+			return args["previous"], nil
+		},
+	}
+	for name, h := range handlers {
+		mustRegisterTool(t, idx, testTool(name), testLocalBackend(name))
+		localReg.Register(name, h)
+	}
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithCheckpointStore(store),
+	)
+	return runner, &healed, &expensiveCalls
+}
+
+func TestResumeChain_ContinuesFromFirstIncompleteStep(t *testing.T) {
+	stores := map[string]CheckpointStore{
+		"memory": NewMemoryCheckpointStore(),
+		"file":   NewFileCheckpointStore(t.TempDir()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			runner, healed, expensiveCalls := flakyChain(t, store)
+			steps := []ChainStep{
+				{ID: "load", ToolID: "expensive", Args: map[string]any{"n": "$.inputs.n"}},
+				{ID: "use", ToolID: "flaky", UsePrevious: true},
+			}
+			ctx := WithRunID(WithChainInputs(context.Background(), map[string]any{"n": 7.0}), "run-1")
+
+			_, results, err := runner.RunChain(ctx, steps)
+			if !errors.Is(err, ErrExecution) {
+				t.Fatalf("RunChain() error = %v, want ErrExecution", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("len(results) = %d, want 2", len(results))
+			}
+
+			cp, err := store.Load(context.Background(), "run-1")
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(cp.Results) != 1 || cp.Done {
+				t.Fatalf("checkpoint has %d results (done=%v), want 1 (done=false)", len(cp.Results), cp.Done)
+			}
+
+			healed.Store(true)
+			final, results, err := runner.ResumeChain(context.Background(), "run-1")
+			if err != nil {
+				t.Fatalf("ResumeChain() error = %v", err)
+			}
+			if got := expensiveCalls.Load(); got != 1 {
+				t.Errorf("expensive step ran %d times, want 1", got)
+			}
+			if len(results) != 2 || results[0].ID != "load" || results[1].ID != "use" {
+				t.Fatalf("results = %+v, want load and use", results)
+			}
+			out, ok := final.Structured.(map[string]any)
+			if !ok || out["n"] != 7.0 {
+				t.Errorf("final.Structured = %v, want map[n:7]", final.Structured)
+			}
+
+			cp, err = store.Load(context.Background(), "run-1")
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !cp.Done || len(cp.Results) != 2 {
+				t.Errorf("checkpoint has %d results (done=%v), want 2 (done=true)", len(cp.Results), cp.Done)
+			}
+
+			// Resuming a finished run replays it without running anything.
+			if _, results, err = runner.ResumeChain(context.Background(), "run-1"); err != nil || len(results) != 2 {
+				t.Errorf("ResumeChain(done) = (%d results, %v), want (2, nil)", len(results), err)
+			}
+			if got := expensiveCalls.Load(); got != 1 {
+				t.Errorf("expensive step ran %d times after replay, want 1", got)
+			}
+		})
+	}
+}
+
+func TestResumeChain_KeepsChainDeadline(t *testing.T) {
+	store := NewFileCheckpointStore(t.TempDir())
+	runner, healed, _ := flakyChain(t, store)
+	steps := []ChainStep{{ToolID: "expensive"}, {ToolID: "flaky"}}
+	ctx := WithChainTimeout(WithRunID(context.Background(), "run-1"), time.Hour)
+
+	start := time.Now()
+	if _, _, err := runner.RunChain(ctx, steps); !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunChain() error = %v, want ErrExecution", err)
+	}
+	cp, err := store.Load(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if d := cp.Deadline.Sub(start); d < time.Hour-time.Minute || d > time.Hour+time.Minute {
+		t.Fatalf("checkpoint Deadline = %v, want about an hour after the start", cp.Deadline)
+	}
+
+	// A resumed run keeps to the recorded deadline, even without a timeout
+	// on its own context.
+	cp.Deadline = time.Now().Add(-time.Second)
+	if err := store.Save(context.Background(), cp); err != nil {
+		t.Fatal(err)
+	}
+	healed.Store(true)
+	if _, _, err := runner.ResumeChain(context.Background(), "run-1"); !errors.Is(err, ErrChainTimeout) {
+		t.Errorf("ResumeChain() error = %v, want ErrChainTimeout", err)
+	}
+
+	// A chain timeout on the resume context replaces the expired deadline.
+	resumeStart := time.Now()
+	if _, _, err := runner.ResumeChain(WithChainTimeout(context.Background(), time.Hour), "run-1"); err != nil {
+		t.Fatalf("ResumeChain() with a new timeout error = %v", err)
+	}
+	cp, err = store.Load(context.Background(), "run-1")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cp.Done || cp.Deadline.Before(resumeStart.Add(time.Hour-time.Minute)) {
+		t.Errorf("checkpoint = %+v, want done with a deadline about an hour after the resume", cp)
+	}
+}
+
+func TestRunChain_NoRunIDSkipsCheckpoint(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	runner, healed, _ := flakyChain(t, store)
+	healed.Store(true)
+
+	steps := []ChainStep{{ID: "load", ToolID: "expensive"}}
+	if _, _, err := runner.RunChain(context.Background(), steps); err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(store.cps) != 0 {
+		t.Errorf("store has %d checkpoints, want 0", len(store.cps))
+	}
+}
+
+func TestResumeChain_NotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		runner *DefaultRunner
+	}{
+		{"no store", NewRunner()},
+		{"memory", NewRunner(WithCheckpointStore(NewMemoryCheckpointStore()))},
+		{"file", NewRunner(WithCheckpointStore(NewFileCheckpointStore(t.TempDir())))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.runner.ResumeChain(context.Background(), "missing")
+			if !errors.Is(err, ErrCheckpointNotFound) {
+				t.Errorf("ResumeChain() error = %v, want ErrCheckpointNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileCheckpointStore_RejectsPathRunIDs(t *testing.T) {
+	store := NewFileCheckpointStore(t.TempDir())
+	for _, id := range []string{"", "..", "a/b", `a\b`} {
+		if err := store.Save(context.Background(), Checkpoint{RunID: id}); err == nil {
+			t.Errorf("Save(%q) error = nil, want error", id)
+		}
+	}
+}
+
+func TestCheckpointStores_Delete(t *testing.T) {
+	stores := map[string]CheckpointStore{
+		"memory": NewMemoryCheckpointStore(),
+		"file":   NewFileCheckpointStore(t.TempDir()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := store.Save(ctx, Checkpoint{RunID: "r"}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := store.Delete(ctx, "r"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := store.Delete(ctx, "r"); err != nil {
+				t.Errorf("Delete(unknown) error = %v, want nil", err)
+			}
+			if _, err := store.Load(ctx, "r"); !errors.Is(err, ErrCheckpointNotFound) {
+				t.Errorf("Load() error = %v, want ErrCheckpointNotFound", err)
+			}
+		})
+	}
+}
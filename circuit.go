@@ -0,0 +1,240 @@
+package toolrun
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// CircuitState is the state of a backend's circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets calls through and counts consecutive failures.
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen fails calls fast with ErrCircuitOpen until the cooldown
+	// period has passed.
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen lets a limited number of probe calls through. A
+	// successful probe closes the circuit; a failed one opens it again.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig configures CircuitBreakers.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens a
+	// circuit. Defaults to 5.
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+
+	// Cooldown is how long a circuit stays open before it lets probe calls
+	// through. Defaults to 30s.
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+
+	// HalfOpenMaxCalls bounds the concurrent probe calls of a half-open
+	// circuit. Defaults to 1.
+	HalfOpenMaxCalls int `json:"halfOpenMaxCalls,omitempty"`
+
+	// IsFailure reports whether a failed call counts against the circuit.
+	// Nil uses DefaultRetryable, so execution failures count while
+	// validation errors do not. Calls ended by the caller's context never
+	// count.
+	IsFailure func(err error) bool `json:"-"`
+}
+
+// CircuitBreakers tracks a circuit breaker per backend instance: an MCP
+// server, a provider, or a local handler. All tools served by the same
+// instance share its circuit. It is safe for concurrent use.
+type CircuitBreakers struct {
+	cfg CircuitBreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the state of a single backend instance's breaker.
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// NewCircuitBreakers creates circuit breakers with the given configuration.
+func NewCircuitBreakers(cfg CircuitBreakerConfig) *CircuitBreakers {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+	return &CircuitBreakers{
+		cfg:      cfg,
+		now:      time.Now,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// State returns the state of the circuit for backend's instance.
+// Open circuits whose cooldown has passed are reported as half-open.
+func (b *CircuitBreakers) State(backend toolmodel.ToolBackend) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked(instanceKey(backend))
+}
+
+// States returns the state of every circuit that has recorded a call, keyed
+// by backend instance ("mcp:<server>", "provider:<id>", or "local:<name>").
+func (b *CircuitBreakers) States() map[string]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make(map[string]CircuitState, len(b.circuits))
+	for key := range b.circuits {
+		states[key] = b.stateLocked(key)
+	}
+	return states
+}
+
+// Reset closes the circuit for backend's instance.
+func (b *CircuitBreakers) Reset(backend toolmodel.ToolBackend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, instanceKey(backend))
+}
+
+func (b *CircuitBreakers) stateLocked(key string) CircuitState {
+	c, ok := b.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= b.cfg.Cooldown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// ready reports whether a call to backend would be let through, without
+// reserving a probe. A nil receiver is always ready.
+func (b *CircuitBreakers) ready(backend toolmodel.ToolBackend) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := instanceKey(backend)
+	switch b.stateLocked(key) {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		c := b.circuits[key]
+		return c.state == CircuitOpen || c.probes < b.cfg.HalfOpenMaxCalls
+	}
+	return true
+}
+
+// allow reports whether a call to backend may proceed, reserving a probe
+// when the circuit is half-open. A nil receiver allows every call.
+func (b *CircuitBreakers) allow(backend toolmodel.ToolBackend) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := instanceKey(backend)
+	c, ok := b.circuits[key]
+	if !ok {
+		return true
+	}
+	switch b.stateLocked(key) {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if c.state == CircuitOpen {
+			c.state = CircuitHalfOpen
+			c.probes = 0
+		}
+		if c.probes >= b.cfg.HalfOpenMaxCalls {
+			return false
+		}
+		c.probes++
+	}
+	return true
+}
+
+// record updates backend's circuit with the outcome of a call let through
+// by allow. A nil receiver records nothing.
+func (b *CircuitBreakers) record(ctx context.Context, backend toolmodel.ToolBackend, err error) {
+	if b == nil {
+		return
+	}
+	failed := err != nil && ctx.Err() == nil && b.isFailure(err)
+	ignored := err != nil && !failed
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := instanceKey(backend)
+	c, ok := b.circuits[key]
+	if !ok {
+		if !failed {
+			return
+		}
+		c = &circuit{state: CircuitClosed}
+		b.circuits[key] = c
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		switch {
+		case failed:
+			c.failures++
+			if c.failures >= b.cfg.FailureThreshold {
+				c.state = CircuitOpen
+				c.openedAt = b.now()
+			}
+		case !ignored:
+			c.failures = 0
+		}
+	case CircuitHalfOpen:
+		if c.probes > 0 {
+			c.probes--
+		}
+		switch {
+		case failed:
+			c.state = CircuitOpen
+			c.openedAt = b.now()
+			c.probes = 0
+		case !ignored:
+			c.state = CircuitClosed
+			c.failures = 0
+			c.probes = 0
+		}
+	}
+}
+
+func (b *CircuitBreakers) isFailure(err error) bool {
+	if b.cfg.IsFailure != nil {
+		return b.cfg.IsFailure(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// instanceKey identifies the backend instance a breaker or limit applies to:
+// an MCP server, a provider, or a local handler.
+func instanceKey(b toolmodel.ToolBackend) string {
+	switch {
+	case b.Kind == toolmodel.BackendKindMCP && b.MCP != nil:
+		return "mcp:" + b.MCP.ServerName
+	case b.Kind == toolmodel.BackendKindProvider && b.Provider != nil:
+		return "provider:" + b.Provider.ProviderID
+	case b.Kind == toolmodel.BackendKindLocal && b.Local != nil:
+		return "local:" + b.Local.Name
+	}
+	return string(b.Kind)
+}
@@ -0,0 +1,168 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// fakeClock is a manually advanced clock for circuit breaker tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreakers(threshold int) (*CircuitBreakers, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := NewCircuitBreakers(CircuitBreakerConfig{FailureThreshold: threshold, Cooldown: time.Minute})
+	b.now = clock.now
+	return b, clock
+}
+
+func TestCircuitBreakers_Run(t *testing.T) {
+	breakers, clock := newTestBreakers(2)
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"svc": func(_ context.Context, _ map[string]any) (any, error) {
+			calls.Add(1)
+			if healthy.Load() {
+				return "ok", nil
+			}
+			return nil, errTest
+		},
+	})
+	runner.cfg.CircuitBreakers = breakers
+	backend := testLocalBackend("svc")
+
+	for range 2 {
+		if _, err := runner.Run(context.Background(), "svc", nil); !errors.Is(err, ErrExecution) {
+			t.Fatalf("Run() error = %v, want ErrExecution", err)
+		}
+	}
+	if got := breakers.State(backend); got != CircuitOpen {
+		t.Fatalf("State() = %s, want open", got)
+	}
+
+	_, err := runner.Run(context.Background(), "svc", nil)
+	var toolErr *ToolError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &toolErr) || toolErr.Op != "circuit_open" {
+		t.Fatalf("Run() error = %v, want ErrCircuitOpen with op circuit_open", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2 (open circuit must not dispatch)", calls.Load())
+	}
+
+	// A failed probe reopens the circuit.
+	clock.advance(time.Minute)
+	if got := breakers.State(backend); got != CircuitHalfOpen {
+		t.Fatalf("State() after cooldown = %s, want half_open", got)
+	}
+	if _, err := runner.Run(context.Background(), "svc", nil); !errors.Is(err, ErrExecution) {
+		t.Fatalf("probe error = %v, want ErrExecution", err)
+	}
+	if got := breakers.State(backend); got != CircuitOpen {
+		t.Fatalf("State() after failed probe = %s, want open", got)
+	}
+
+	// A successful probe closes it.
+	clock.advance(time.Minute)
+	healthy.Store(true)
+	if _, err := runner.Run(context.Background(), "svc", nil); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if got := breakers.State(backend); got != CircuitClosed {
+		t.Errorf("State() after successful probe = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakers_SkipsOpenBackend(t *testing.T) {
+	for _, failover := range []bool{false, true} {
+		breakers, _ := newTestBreakers(1)
+		runner, provider, _ := newFailoverTestRunner(t,
+			WithCircuitBreakers(breakers),
+			WithFailover(FailoverPolicy{Enabled: failover}))
+		provider.CallToolResult = "from provider"
+
+		// The first call opens the local circuit (and fails over, if enabled).
+		_, _ = runner.Run(context.Background(), "multi", nil)
+
+		result, err := runner.Run(context.Background(), "multi", nil)
+		if err != nil {
+			t.Fatalf("failover=%v: Run() error = %v", failover, err)
+		}
+		if got := backendKinds(result.BackendsTried); len(got) != 1 || got[0] != toolmodel.BackendKindProvider {
+			t.Errorf("failover=%v: BackendsTried = %v, want [provider]", failover, got)
+		}
+	}
+}
+
+func TestCircuitBreakers_IgnoresValidationErrors(t *testing.T) {
+	breakers, _ := newTestBreakers(1)
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testToolWithOutputSchema("bad"), testLocalBackend("bad"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("bad", func(_ context.Context, _ map[string]any) (any, error) {
+		return "not an object", nil
+	})
+	runner := NewRunner(WithIndex(idx), WithLocalRegistry(localReg), WithCircuitBreakers(breakers))
+
+	for range 3 {
+		if _, err := runner.Run(context.Background(), "bad", nil); !errors.Is(err, ErrOutputValidation) {
+			t.Fatalf("Run() error = %v, want ErrOutputValidation", err)
+		}
+	}
+	if got := breakers.State(testLocalBackend("bad")); got != CircuitClosed {
+		t.Errorf("State() = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakers_SharedByInstance(t *testing.T) {
+	breakers, _ := newTestBreakers(1)
+	breakers.record(context.Background(), testProviderBackend("p", "a"), WrapError("a", nil, "execute", ErrExecution))
+
+	if got := breakers.State(testProviderBackend("p", "b")); got != CircuitOpen {
+		t.Errorf("State() of another tool on the same provider = %s, want open", got)
+	}
+	if got := breakers.State(testProviderBackend("q", "a")); got != CircuitClosed {
+		t.Errorf("State() of another provider = %s, want closed", got)
+	}
+	if states := breakers.States(); len(states) != 1 || states["provider:p"] != CircuitOpen {
+		t.Errorf("States() = %v, want provider:p open", states)
+	}
+
+	breakers.Reset(testProviderBackend("p", "a"))
+	if got := breakers.State(testProviderBackend("p", "a")); got != CircuitClosed {
+		t.Errorf("State() after Reset = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakers_HalfOpenProbes(t *testing.T) {
+	breakers, clock := newTestBreakers(1)
+	backend := testMCPBackend("srv")
+	failure := WrapError("t", nil, "execute", ErrExecution)
+	breakers.record(context.Background(), backend, failure)
+	clock.advance(time.Minute)
+
+	if !breakers.allow(backend) {
+		t.Fatal("allow() = false, want a probe after cooldown")
+	}
+	if breakers.allow(backend) {
+		t.Error("allow() = true, want a single concurrent probe")
+	}
+
+	// A probe ended by the caller's context releases its slot.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	breakers.record(ctx, backend, failure)
+	if got := breakers.State(backend); got != CircuitHalfOpen {
+		t.Errorf("State() = %s, want half_open", got)
+	}
+	if !breakers.allow(backend) {
+		t.Error("allow() = false, want the released probe slot")
+	}
+}
@@ -0,0 +1,251 @@
+package toolrun
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CoercionPolicy controls schema-driven coercion of args before input
+// validation, for callers such as LLMs that send "5" for an integer or a
+// single value for an array. The zero value disables coercion.
+//
+// Coercion only changes values whose type the InputSchema does not allow,
+// converting them to the first allowed type they convert to. In the default
+// strict mode conversions are lossless:
+//   - strings holding a JSON number become integers or numbers
+//   - "true" and "false" become booleans
+//   - a single value becomes a one-element array
+//
+// Lenient mode additionally trims whitespace, accepts integral numbers such
+// as "5.0" for integers, accepts "1"/"0", "yes"/"no" and any case of
+// "true"/"false" for booleans, and converts numbers and booleans to strings.
+type CoercionPolicy struct {
+	// Enabled turns on coercion.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Lenient enables the lossy conversions of lenient mode.
+	Lenient bool `json:"lenient,omitempty"`
+}
+
+// Coercion records a value changed by coercion.
+type Coercion struct {
+	// Path is the JSON pointer of the value within the args, such as
+	// "/filters/0/limit".
+	Path string `json:"path"`
+
+	// From and To are the values before and after coercion.
+	From any `json:"from"`
+	To   any `json:"to"`
+
+	// Type is the schema type the value was coerced to.
+	Type string `json:"type"`
+}
+
+// coerceArgs coerces args to the types allowed by schema. args is never
+// modified; changed maps and slices are copied.
+func coerceArgs(schema any, args map[string]any, lenient bool) (map[string]any, []Coercion) {
+	s, ok := schemaObject(schema)
+	if !ok || args == nil {
+		return args, nil
+	}
+	c := &coercer{lenient: lenient}
+	out, changed := c.coerce(s, args, "")
+	if !changed {
+		return args, nil
+	}
+	return out.(map[string]any), c.applied
+}
+
+// coercer accumulates the coercions applied to one call's args.
+type coercer struct {
+	lenient bool
+	applied []Coercion
+}
+
+// coerce coerces v and the values nested in it, reporting whether the
+// returned value differs from v.
+func (c *coercer) coerce(schema map[string]any, v any, path string) (any, bool) {
+	changed := false
+	if types := schemaTypes(schema); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasType(v, t) }) {
+		for _, t := range types {
+			if to, ok := c.convert(v, t); ok {
+				c.applied = append(c.applied, Coercion{Path: path, From: v, To: to, Type: t})
+				v, changed = to, true
+				break
+			}
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		var out map[string]any
+		for _, name := range slices.Sorted(maps.Keys(props)) {
+			prop, ok := props[name].(map[string]any)
+			cur, present := val[name]
+			if !ok || !present {
+				continue
+			}
+			next, ok := c.coerce(prop, cur, path+"/"+escapePointer(name))
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = maps.Clone(val)
+			}
+			out[name] = next
+		}
+		if out != nil {
+			return out, true
+		}
+
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		if items == nil {
+			break
+		}
+		var out []any
+		for i, item := range val {
+			next, ok := c.coerce(items, item, path+"/"+strconv.Itoa(i))
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(val)
+			}
+			out[i] = next
+		}
+		if out != nil {
+			return out, true
+		}
+	}
+	return v, changed
+}
+
+// maxExactInt is the largest integer a float64 holds exactly.
+const maxExactInt = 1 << 53
+
+// convert converts v to schema type t, or reports false.
+func (c *coercer) convert(v any, t string) (any, bool) {
+	switch t {
+	case "integer", "number":
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		if c.lenient {
+			s = strings.TrimSpace(s)
+		}
+		if !isJSONNumber(s) {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, false
+		}
+		if t == "number" {
+			return f, true
+		}
+		if !c.lenient {
+			// Strict mode only accepts integer literals that a float64
+			// holds exactly.
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n > maxExactInt || n < -maxExactInt {
+				return nil, false
+			}
+			return float64(n), true
+		}
+		if math.Trunc(f) != f || math.Abs(f) > maxExactInt {
+			return nil, false
+		}
+		return f, true
+
+	case "boolean":
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		if c.lenient {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "true", "1", "yes":
+				return true, true
+			case "false", "0", "no":
+				return false, true
+			}
+			return nil, false
+		}
+		switch s {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+
+	case "string":
+		if !c.lenient {
+			return nil, false
+		}
+		switch val := v.(type) {
+		case bool:
+			return strconv.FormatBool(val), true
+		case float64:
+			return strconv.FormatFloat(val, 'f', -1, 64), true
+		}
+		if rv := reflect.ValueOf(v); rv.CanInt() {
+			return strconv.FormatInt(rv.Int(), 10), true
+		} else if rv.CanUint() {
+			return strconv.FormatUint(rv.Uint(), 10), true
+		}
+
+	case "array":
+		if v != nil {
+			return []any{v}, true
+		}
+	}
+	return nil, false
+}
+
+// hasType reports whether v is a JSON value of schema type t.
+func hasType(v any, t string) bool {
+	if v == nil {
+		return t == "null"
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt(), rv.CanUint():
+		return t == "integer" || t == "number"
+	case rv.CanFloat():
+		f := rv.Float()
+		return t == "number" || t == "integer" && math.Trunc(f) == f
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return t == "boolean"
+	case reflect.String:
+		return t == "string"
+	case reflect.Slice, reflect.Array:
+		return t == "array"
+	case reflect.Map, reflect.Struct:
+		return t == "object"
+	}
+	return false
+}
+
+// isJSONNumber reports whether s is a JSON number literal.
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	var n json.Number
+	return json.Unmarshal([]byte(s), &n) == nil
+}
+
+// escapePointer escapes a key for use as a JSON pointer token.
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,153 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func coerceTestSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"count":   map[string]any{"type": "integer"},
+			"ratio":   map[string]any{"type": "number"},
+			"enabled": map[string]any{"type": "boolean"},
+			"name":    map[string]any{"type": "string"},
+			"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"ids":     map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+			"maybe":   map[string]any{"type": []any{"integer", "null"}},
+			"a/b":     map[string]any{"type": "integer"},
+		},
+	}
+}
+
+func TestCoerceArgs_Strict(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+		path string
+	}{
+		{"integer", map[string]any{"count": "5"}, map[string]any{"count": 5.0}, "/count"},
+		{"negative integer", map[string]any{"count": "-5"}, map[string]any{"count": -5.0}, "/count"},
+		{"number", map[string]any{"ratio": "0.5"}, map[string]any{"ratio": 0.5}, "/ratio"},
+		{"boolean", map[string]any{"enabled": "true"}, map[string]any{"enabled": true}, "/enabled"},
+		{"single value to array", map[string]any{"tags": "x"}, map[string]any{"tags": []any{"x"}}, "/tags"},
+		{"array items", map[string]any{"ids": []any{1.0, "2"}}, map[string]any{"ids": []any{1.0, 2.0}}, "/ids/1"},
+		{"type union", map[string]any{"maybe": "7"}, map[string]any{"maybe": 7.0}, "/maybe"},
+		{"escaped path", map[string]any{"a/b": "1"}, map[string]any{"a/b": 1.0}, "/a~1b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := coerceArgs(coerceTestSchema(), tt.args, false)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coerceArgs() = %v, want %v", got, tt.want)
+			}
+			if len(applied) != 1 || applied[0].Path != tt.path {
+				t.Errorf("applied = %+v, want one coercion at %s", applied, tt.path)
+			}
+		})
+	}
+}
+
+func TestCoerceArgs_StrictRejects(t *testing.T) {
+	for _, args := range []map[string]any{
+		{"count": "5.0"},
+		{"count": " 5"},
+		{"count": "9007199254740993"},
+		{"count": "five"},
+		{"ratio": "NaN"},
+		{"ratio": "0x10"},
+		{"enabled": "yes"},
+		{"enabled": "True"},
+		{"name": 5.0},
+		{"count": 5.5},
+	} {
+		got, applied := coerceArgs(coerceTestSchema(), args, false)
+		if len(applied) != 0 || !reflect.DeepEqual(got, args) {
+			t.Errorf("coerceArgs(%v) = %v, %+v; want unchanged", args, got, applied)
+		}
+	}
+}
+
+func TestCoerceArgs_Lenient(t *testing.T) {
+	args := map[string]any{
+		"count":   " 5.0 ",
+		"enabled": "Yes",
+		"name":    42.0,
+		"tags":    []any{true, 1.5},
+	}
+	got, applied := coerceArgs(coerceTestSchema(), args, true)
+	want := map[string]any{
+		"count":   5.0,
+		"enabled": true,
+		"name":    "42",
+		"tags":    []any{"true", "1.5"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("coerceArgs() = %v, want %v", got, want)
+	}
+	if len(applied) != 5 {
+		t.Errorf("applied %d coercions, want 5: %+v", len(applied), applied)
+	}
+}
+
+func TestCoerceArgs_DoesNotModifyArgs(t *testing.T) {
+	ids := []any{"1"}
+	args := map[string]any{"count": "5", "ids": ids}
+
+	coerceArgs(coerceTestSchema(), args, false)
+
+	if args["count"] != "5" || ids[0] != "1" {
+		t.Errorf("args modified: %v", args)
+	}
+}
+
+func newCoercionTestRunner(t *testing.T, policy CoercionPolicy) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	tool := testTool("page")
+	tool.InputSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit": map[string]any{"type": "integer"},
+		},
+	}
+	mustRegisterTool(t, idx, tool, testLocalBackend("page"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("page", func(_ context.Context, args map[string]any) (any, error) {
+		return args["limit"], nil
+	})
+	return NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(true, false),
+		WithCoercion(policy),
+	)
+}
+
+func TestRun_Coercion(t *testing.T) {
+	runner := newCoercionTestRunner(t, CoercionPolicy{Enabled: true})
+
+	result, err := runner.Run(context.Background(), "page", map[string]any{"limit": "5"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != 5.0 {
+		t.Errorf("Structured = %v, want 5", result.Structured)
+	}
+	want := []Coercion{{Path: "/limit", From: "5", To: 5.0, Type: "integer"}}
+	if !reflect.DeepEqual(result.Coercions, want) {
+		t.Errorf("Coercions = %+v, want %+v", result.Coercions, want)
+	}
+}
+
+func TestRun_CoercionDisabled(t *testing.T) {
+	runner := newCoercionTestRunner(t, CoercionPolicy{})
+
+	if _, err := runner.Run(context.Background(), "page", map[string]any{"limit": "5"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Run() error = %v, want ErrValidation", err)
+	}
+}
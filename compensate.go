@@ -0,0 +1,101 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+)
+
+// checkCompensation statically validates a step's compensation references.
+// visible must include the step itself.
+func checkCompensation(step ChainStep, visible map[string]bool) error {
+	if step.Compensate == nil {
+		return nil
+	}
+	if step.Compensate.ToolID == "" {
+		return WrapError(step.ToolID, nil, "compensate", ErrInvalidToolID)
+	}
+	if err := checkRefs(step.Compensate.Args, visible, false); err != nil {
+		return WrapError(step.Compensate.ToolID, nil, "compensate", err)
+	}
+	return nil
+}
+
+// compensable reports whether a step must be compensated when its chain
+// aborts: it declares a compensation and ran successfully, without being
+// skipped or handled by an error policy.
+func compensable(step ChainStep, sr StepResult) bool {
+	return step.Compensate != nil && sr.Err == nil && sr.Policy == "" && !sr.Skipped
+}
+
+// runCompensation executes a step's compensating tool call.
+func (r *DefaultRunner) runCompensation(ctx context.Context, step ChainStep, scope *chainScope) StepResult {
+	comp := step.Compensate
+	sr := StepResult{ID: step.ID, ToolID: comp.ToolID}
+
+	resolved, err := resolveRefs(comp.Args, scope)
+	if err != nil {
+		sr.Err = WrapError(comp.ToolID, nil, "compensate", err)
+		return sr
+	}
+	args, _ := resolved.(map[string]any)
+
+	sr.Result, sr.Err = r.Run(ctx, comp.ToolID, args)
+	sr.Backend = stepBackend(sr.Result, sr.Err)
+	sr.BackendsTried = stepTried(sr.Result, sr.Err)
+	return sr
+}
+
+// compensateChain runs the compensations of completed chain steps in reverse
+// order after the chain aborted with cause, recording each on its
+// StepResult. Compensations run even if ctx was canceled. It returns the
+// index of the earliest compensated step (len(results) if none was), and
+// cause joined with any compensation errors.
+func (r *DefaultRunner) compensateChain(ctx context.Context, steps []ChainStep, results []StepResult, scope *chainScope, cause error) (int, error) {
+	ctx = context.WithoutCancel(ctx)
+	errs := []error{cause}
+	undone := len(results)
+	for i := len(results) - 1; i >= 0; i-- {
+		if !compensable(steps[i], results[i]) {
+			continue
+		}
+		comp := r.runCompensation(ctx, steps[i], scope)
+		results[i].Compensation = &comp
+		undone = i
+		if comp.Err != nil {
+			errs = append(errs, comp.Err)
+		}
+	}
+	if len(errs) == 1 {
+		return undone, cause
+	}
+	return undone, errors.Join(errs...)
+}
+
+// compensateGraph runs the compensations of completed graph steps in reverse
+// completion order after the graph aborted with cause. Each compensation sees
+// the step and its ancestors. It returns cause joined with any compensation
+// errors.
+func (r *DefaultRunner) compensateGraph(ctx context.Context, g *stepGraph, steps []GraphStep, results []StepResult, order []int, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	inputs := chainInputs(ctx)
+	errs := []error{cause}
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		if !compensable(steps[i].ChainStep, results[i]) {
+			continue
+		}
+		scope := g.scope(i, steps[i], results)
+		scope.inputs = inputs
+		self := results[i]
+		scope.steps[i] = &self
+		comp := r.runCompensation(ctx, steps[i].ChainStep, scope)
+		results[i].Compensation = &comp
+		if comp.Err != nil {
+			errs = append(errs, comp.Err)
+		}
+	}
+	if len(errs) == 1 {
+		return cause
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,261 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// compensationRecorder records the order and args of compensating calls.
+type compensationRecorder struct {
+	mu    sync.Mutex
+	calls []map[string]any
+}
+
+func (c *compensationRecorder) handler(name string) LocalHandler {
+	return func(_ context.Context, args map[string]any) (any, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.calls = append(c.calls, map[string]any{"tool": name, "args": args})
+		return "undone", nil
+	}
+}
+
+func TestRunChain_CompensatesInReverseOrder(t *testing.T) {
+	rec := &compensationRecorder{}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"create": func(_ context.Context, args map[string]any) (any, error) {
+			return map[string]any{"id": args["name"]}, nil
+		},
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"cancel_ticket":  rec.handler("cancel_ticket"),
+		"release_server": rec.handler("release_server"),
+	})
+
+	steps := []ChainStep{
+		{ID: "ticket", ToolID: "create", Args: map[string]any{"name": "T-1"},
+			Compensate: &Compensation{ToolID: "cancel_ticket", Args: map[string]any{"id": "$.steps.ticket.structured.id"}}},
+		{ID: "server", ToolID: "create", Args: map[string]any{"name": "srv"},
+			Compensate: &Compensation{ToolID: "release_server", Args: map[string]any{"id": "{{steps.server.structured.id}}"}}},
+		{ID: "deploy", ToolID: "fail",
+			Compensate: &Compensation{ToolID: "release_server"}},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunChain() error = %v, want ErrExecution", err)
+	}
+	if len(rec.calls) != 2 {
+		t.Fatalf("compensations = %v, want 2 calls", rec.calls)
+	}
+	if rec.calls[0]["tool"] != "release_server" || rec.calls[1]["tool"] != "cancel_ticket" {
+		t.Errorf("compensation order = %v, want release_server then cancel_ticket", rec.calls)
+	}
+	if id := rec.calls[1]["args"].(map[string]any)["id"]; id != "T-1" {
+		t.Errorf("cancel_ticket id = %v, want T-1", id)
+	}
+	if id := rec.calls[0]["args"].(map[string]any)["id"]; id != "srv" {
+		t.Errorf("release_server id = %v, want srv", id)
+	}
+
+	for i := 0; i < 2; i++ {
+		comp := results[i].Compensation
+		if comp == nil || comp.Err != nil || comp.Result.Structured != "undone" {
+			t.Errorf("results[%d].Compensation = %+v, want successful undone", i, comp)
+		}
+	}
+	if results[2].Compensation != nil {
+		t.Error("failed step should not be compensated")
+	}
+}
+
+func TestRunChain_CompensationErrorsJoined(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"ok": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"undo": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errors.New("undo failed")
+		},
+	})
+
+	steps := []ChainStep{
+		{ToolID: "ok", Compensate: &Compensation{ToolID: "undo"}},
+		{ToolID: "fail"},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunChain() error = %v, want ErrExecution", err)
+	}
+	if !strings.Contains(err.Error(), "undo failed") {
+		t.Errorf("RunChain() error = %v, want compensation error included", err)
+	}
+	if results[0].Compensation == nil || results[0].Compensation.Err == nil {
+		t.Errorf("results[0].Compensation = %+v, want error", results[0].Compensation)
+	}
+}
+
+func TestRunChain_NoCompensationOnSuccess(t *testing.T) {
+	rec := &compensationRecorder{}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"ok":   func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+		"undo": rec.handler("undo"),
+	})
+
+	steps := []ChainStep{{ToolID: "ok", Compensate: &Compensation{ToolID: "undo"}}}
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(rec.calls) != 0 || results[0].Compensation != nil {
+		t.Errorf("compensations ran on success: %v", rec.calls)
+	}
+}
+
+func TestRunChain_CompensationSkipsHandledSteps(t *testing.T) {
+	rec := &compensationRecorder{}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"undo": rec.handler("undo"),
+	})
+
+	steps := []ChainStep{
+		{ToolID: "fail", OnError: &ErrorPolicy{Action: ErrorActionContinue}, Compensate: &Compensation{ToolID: "undo"}},
+		{ToolID: "fail", When: "false", Compensate: &Compensation{ToolID: "undo"}},
+		{ToolID: "fail"},
+	}
+	if _, _, err := runner.RunChain(context.Background(), steps); err == nil {
+		t.Fatal("RunChain() error = nil, want error")
+	}
+	if len(rec.calls) != 0 {
+		t.Errorf("compensations = %v, want none for failed or skipped steps", rec.calls)
+	}
+}
+
+func TestRunChain_CompensationRunsAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := &compensationRecorder{}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"ok": func(_ context.Context, _ map[string]any) (any, error) {
+			cancel()
+			return "ok", nil
+		},
+		"undo": rec.handler("undo"),
+	})
+
+	steps := []ChainStep{
+		{ToolID: "ok", Compensate: &Compensation{ToolID: "undo"}},
+		{ToolID: "ok"},
+	}
+	_, _, err := runner.RunChain(ctx, steps)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunChain() error = %v, want context.Canceled", err)
+	}
+	if len(rec.calls) != 1 {
+		t.Errorf("compensations = %d, want 1", len(rec.calls))
+	}
+}
+
+func TestRunChain_CompensationTrimsCheckpoint(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	for name, h := range map[string]LocalHandler{
+		"read":   func(_ context.Context, _ map[string]any) (any, error) { return "data", nil },
+		"create": func(_ context.Context, _ map[string]any) (any, error) { return "ticket", nil },
+		"fail":   func(_ context.Context, _ map[string]any) (any, error) { return nil, errTest },
+		"undo":   func(_ context.Context, _ map[string]any) (any, error) { return nil, nil },
+	} {
+		mustRegisterTool(t, idx, testTool(name), testLocalBackend(name))
+		localReg.Register(name, h)
+	}
+	runner := NewRunner(WithIndex(idx), WithLocalRegistry(localReg), WithValidation(false, false), WithCheckpointStore(store))
+
+	steps := []ChainStep{
+		{ToolID: "read"},
+		{ToolID: "create", Compensate: &Compensation{ToolID: "undo"}},
+		{ToolID: "fail"},
+	}
+	if _, _, err := runner.RunChain(WithRunID(context.Background(), "r"), steps); err == nil {
+		t.Fatal("RunChain() error = nil, want error")
+	}
+	cp, err := store.Load(context.Background(), "r")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cp.Results) != 1 {
+		t.Errorf("checkpoint has %d results, want 1 (compensated step dropped)", len(cp.Results))
+	}
+}
+
+func TestRunChain_InvalidCompensation(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"ok": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+
+	tests := []struct {
+		name  string
+		steps []ChainStep
+		want  error
+	}{
+		{
+			name:  "missing tool",
+			steps: []ChainStep{{ToolID: "ok", Compensate: &Compensation{}}},
+			want:  ErrInvalidToolID,
+		},
+		{
+			name: "later step reference",
+			steps: []ChainStep{
+				{ToolID: "ok", Compensate: &Compensation{ToolID: "ok", Args: map[string]any{"x": "$.steps[1].result"}}},
+				{ToolID: "ok"},
+			},
+			want: ErrInvalidReference,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := runner.RunChain(context.Background(), tt.steps); !errors.Is(err, tt.want) {
+				t.Errorf("RunChain() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunGraph_CompensatesCompletedSteps(t *testing.T) {
+	rec := &compensationRecorder{}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"ok": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"undo": rec.handler("undo"),
+	})
+
+	steps := []GraphStep{
+		{ChainStep: ChainStep{ID: "a", ToolID: "ok", Compensate: &Compensation{ToolID: "undo", Args: map[string]any{"v": "$.steps.a.structured"}}}},
+		{ChainStep: ChainStep{ID: "b", ToolID: "fail"}, DependsOn: []string{"a"}},
+	}
+	results, err := runner.RunGraph(context.Background(), steps, 0)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunGraph() error = %v, want ErrExecution", err)
+	}
+	if len(rec.calls) != 1 {
+		t.Fatalf("compensations = %v, want 1", rec.calls)
+	}
+	if v := rec.calls[0]["args"].(map[string]any)["v"]; v != "ok" {
+		t.Errorf("compensation arg = %v, want ok", v)
+	}
+	if results[0].Compensation == nil {
+		t.Error("results[0].Compensation = nil, want recorded compensation")
+	}
+}
@@ -0,0 +1,110 @@
+package toolrun
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// CompositeTool publishes a chain as a tool. The tool's input arguments
+// become the chain inputs (see WithChainInputs) and the chain's final
+// Structured value becomes the tool's result, so the tool's InputSchema and
+// OutputSchema describe the chain as a whole.
+//
+// To make a composite tool callable, register Tool with Backend in the index
+// and Handler under Backend().Local.Name in the LocalRegistry:
+//
+//	ct, err := toolrun.NewCompositeTool(tool, steps)
+//	_ = idx.RegisterTool(ct.Tool, ct.Backend())
+//	localReg.Register(ct.Backend().Local.Name, ct.Handler(runner))
+//
+// When DefaultRunner runs a composite tool, the nested chain's step results
+// are recorded in RunResult.Steps, or in ToolError.Steps when it fails.
+type CompositeTool struct {
+	// Tool is the published tool definition.
+	Tool toolmodel.Tool
+
+	// Steps are the chain steps run for each call.
+	Steps []ChainStep
+}
+
+// NewCompositeTool validates tool and steps and returns a composite tool.
+// Steps are checked as RunChain would check them; tools are not resolved
+// (see DefaultRunner.CheckChain).
+func NewCompositeTool(tool toolmodel.Tool, steps []ChainStep) (*CompositeTool, error) {
+	if err := tool.Validate(); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, WrapError(tool.ToolID(), nil, "check_chain", ErrInvalidChain)
+	}
+	if err := validateChainShape(steps); err != nil {
+		return nil, err
+	}
+	return &CompositeTool{Tool: tool, Steps: steps}, nil
+}
+
+// Backend returns the local backend the composite tool is registered with.
+// Its handler name is the tool ID.
+func (c *CompositeTool) Backend() toolmodel.ToolBackend {
+	return toolmodel.ToolBackend{
+		Kind:  toolmodel.BackendKindLocal,
+		Local: &toolmodel.LocalBackend{Name: c.Tool.ToolID()},
+	}
+}
+
+// Handler returns the LocalHandler that runs the chain with runner.
+// The chain runs with the call's args as its inputs; run IDs and chain
+// timeouts attached to the caller's context do not apply to it, though the
+// caller's deadline does.
+func (c *CompositeTool) Handler(runner Runner) LocalHandler {
+	return func(ctx context.Context, args map[string]any) (any, error) {
+		nested := nestedStepsFrom(ctx)
+		ctx = WithChainInputs(ctx, args)
+		ctx = WithRunID(ctx, "")
+		ctx = WithChainTimeout(ctx, 0)
+		final, results, err := runner.RunChain(ctx, c.Steps)
+		if nested != nil {
+			nested.set(results)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return final.Structured, nil
+	}
+}
+
+// nestedSteps collects the step results of a composite tool's chain for the
+// local dispatch that called it.
+type nestedSteps struct {
+	mu    sync.Mutex
+	steps []StepResult
+}
+
+// nestedStepsKey is the context key for the active nestedSteps collector.
+type nestedStepsKey struct{}
+
+// withNestedSteps returns a context carrying a fresh collector.
+func withNestedSteps(ctx context.Context) (context.Context, *nestedSteps) {
+	nested := &nestedSteps{}
+	return context.WithValue(ctx, nestedStepsKey{}, nested), nested
+}
+
+// nestedStepsFrom returns the collector attached to ctx, if any.
+func nestedStepsFrom(ctx context.Context) *nestedSteps {
+	nested, _ := ctx.Value(nestedStepsKey{}).(*nestedSteps)
+	return nested
+}
+
+func (n *nestedSteps) set(steps []StepResult) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.steps = steps
+}
+
+func (n *nestedSteps) get() []StepResult {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.steps
+}
@@ -0,0 +1,138 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// newCompositeTestRunner registers "lookup" and "greet" local tools and the
+// composite "users:onboard", which chains them.
+func newCompositeTestRunner(t *testing.T) *DefaultRunner {
+	t.Helper()
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"lookup": func(_ context.Context, args map[string]any) (any, error) {
+			if args["email"] == "" {
+				return nil, errTest
+			}
+			return map[string]any{"name": "Ada", "email": args["email"]}, nil
+		},
+		"greet": func(_ context.Context, args map[string]any) (any, error) {
+			user := args["previous"].(map[string]any)
+			return "welcome " + user["name"].(string), nil
+		},
+	})
+
+	ct, err := NewCompositeTool(testToolWithNamespace("users", "onboard"), []ChainStep{
+		{ID: "lookup", ToolID: "lookup", Args: map[string]any{"email": "$.inputs.email"}},
+		{ToolID: "greet", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("NewCompositeTool() error = %v", err)
+	}
+	mustRegisterTool(t, runner.cfg.Index.(*mockIndex), ct.Tool, ct.Backend())
+	runner.cfg.Local.(*mockLocalRegistry).Register(ct.Backend().Local.Name, ct.Handler(runner))
+	return runner
+}
+
+func TestCompositeTool_Run(t *testing.T) {
+	runner := newCompositeTestRunner(t)
+
+	result, err := runner.Run(context.Background(), "users:onboard", map[string]any{"email": "ada@example.com"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != "welcome Ada" {
+		t.Errorf("Structured = %v, want welcome Ada", result.Structured)
+	}
+	if len(result.Steps) != 2 {
+		t.Fatalf("len(Steps) = %d, want 2", len(result.Steps))
+	}
+	if result.Steps[0].ID != "lookup" || result.Steps[1].ToolID != "greet" {
+		t.Errorf("Steps = %+v, want lookup then greet", result.Steps)
+	}
+}
+
+func TestCompositeTool_NestedInChain(t *testing.T) {
+	runner := newCompositeTestRunner(t)
+
+	ctx := WithChainInputs(context.Background(), map[string]any{"addr": "ada@example.com"})
+	final, results, err := runner.RunChain(ctx, []ChainStep{
+		{ToolID: "users:onboard", Args: map[string]any{"email": "$.inputs.addr"}},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if final.Structured != "welcome Ada" {
+		t.Errorf("final.Structured = %v, want welcome Ada", final.Structured)
+	}
+	nested := results[0].Result.Steps
+	if len(nested) != 2 {
+		t.Fatalf("len(nested) = %d, want 2", len(nested))
+	}
+	user := nested[0].Result.Structured.(map[string]any)
+	if user["email"] != "ada@example.com" {
+		t.Errorf("nested lookup = %v, want email from parent args", user)
+	}
+}
+
+func TestCompositeTool_Failure(t *testing.T) {
+	runner := newCompositeTestRunner(t)
+
+	_, err := runner.Run(context.Background(), "users:onboard", map[string]any{"email": ""})
+	if !errors.Is(err, ErrExecution) {
+		t.Errorf("Run() error = %v, want ErrExecution", err)
+	}
+	// The nested chain's steps up to the failure are kept on the error.
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("Run() error = %v, want ToolError", err)
+	}
+	if len(toolErr.Steps) != 1 || toolErr.Steps[0].ID != "lookup" || !errors.Is(toolErr.Steps[0].Err, ErrExecution) {
+		t.Errorf("ToolError.Steps = %+v, want the failed lookup step", toolErr.Steps)
+	}
+}
+
+func TestCompositeTool_RunIDNotShared(t *testing.T) {
+	runner := newCompositeTestRunner(t)
+	store := NewMemoryCheckpointStore()
+	runner.cfg.CheckpointStore = store
+
+	ctx := WithRunID(context.Background(), "parent")
+	_, _, err := runner.RunChain(ctx, []ChainStep{
+		{ToolID: "users:onboard", Args: map[string]any{"email": "ada@example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	cp, err := store.Load(context.Background(), "parent")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cp.Steps) != 1 || cp.Steps[0].ToolID != "users:onboard" {
+		t.Errorf("checkpoint steps = %+v, want the parent chain", cp.Steps)
+	}
+}
+
+func TestNewCompositeTool_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		tool  toolmodel.Tool
+		steps []ChainStep
+		want  error
+	}{
+		{"invalid tool", toolmodel.Tool{}, []ChainStep{{ToolID: "a"}}, toolmodel.ErrInvalidTool},
+		{"no steps", testTool("c"), nil, ErrInvalidChain},
+		{"bad reference", testTool("c"), []ChainStep{{ToolID: "a", Args: map[string]any{"x": "$.steps.nope"}}}, ErrInvalidReference},
+		{"bad policy", testTool("c"), []ChainStep{{ToolID: "a", OnError: &ErrorPolicy{Action: ErrorActionFallback}}}, ErrInvalidChain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCompositeTool(tt.tool, tt.steps); !errors.Is(err, tt.want) {
+				t.Errorf("NewCompositeTool() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -24,6 +24,10 @@ type Config struct {
 	// Defaults to toolindex.DefaultBackendSelector (local > provider > mcp).
 	BackendSelector toolindex.BackendSelector
 
+	// BackendObserver, when set, is told the latency and outcome of every
+	// call dispatched to a backend, for example to feed an AdaptiveSelector.
+	BackendObserver BackendObserver
+
 	// Validation
 
 	// Validator validates tool inputs and outputs against JSON Schema.
@@ -38,6 +42,20 @@ type Config struct {
 	// Defaults to true.
 	ValidateOutput bool
 
+	// ApplySchemaDefaults fills in the "default" values of the tool's
+	// InputSchema, recursively, on a copy of the args before input
+	// validation. Defaults to false.
+	ApplySchemaDefaults bool
+
+	// Coercion controls schema-driven coercion of args, after schema
+	// defaults and before input validation. The zero value disables it.
+	Coercion CoercionPolicy
+
+	// Secrets resolves secret references such as {"$secret": "github/token"}
+	// in args just before each backend call, and their values are redacted
+	// from errors, results, and stream events. Nil leaves references as they are.
+	Secrets SecretProvider
+
 	// Executors
 
 	// MCP is the executor for MCP backend tools.
@@ -48,6 +66,56 @@ type Config struct {
 
 	// Local is the registry for local handler functions.
 	Local LocalRegistry
+
+	// Resilience
+
+	// RetryPolicy controls how Run retries failed attempts. The zero value
+	// makes a single attempt. WithCallRetryPolicy overrides it per call.
+	RetryPolicy RetryPolicy
+
+	// Failover controls failover from a tool's selected backend to its
+	// alternates. The zero value disables failover.
+	Failover FailoverPolicy
+
+	// CircuitBreakers, when set, tracks a circuit breaker per backend
+	// instance. Backends with open circuits are skipped in favor of
+	// alternates, and calls to them fail fast with ErrCircuitOpen.
+	CircuitBreakers *CircuitBreakers
+
+	// Limiter, when set, enforces concurrency and rate limits per tool,
+	// namespace, MCP server, and provider.
+	Limiter *Limiter
+
+	// Timeouts sets default execution timeouts per backend kind, with
+	// per-tool overrides. The zero value imposes no timeouts.
+	Timeouts TimeoutConfig
+
+	// Hedging controls hedged requests to alternate backends for idempotent
+	// and read-only tools. The zero value disables hedging.
+	Hedging HedgePolicy
+
+	// Interception
+
+	// Interceptors wrap every tool call, including RunStream and chain
+	// steps, in order: the first is outermost.
+	Interceptors []Interceptor
+
+	// StreamInterceptors wrap the event channel of every RunStream call in
+	// order, outside Interceptors: the first is outermost.
+	StreamInterceptors []StreamInterceptor
+
+	// Hooks are typed callbacks for the phases of every tool call.
+	Hooks Hooks
+
+	// Chains
+
+	// ChainErrorPolicy is the error policy for chain steps that do not set
+	// ChainStep.OnError. The zero value aborts on the first error.
+	ChainErrorPolicy ErrorPolicy
+
+	// CheckpointStore persists the progress of chains run with a run ID
+	// (see WithRunID) so that they can be resumed. Nil disables checkpointing.
+	CheckpointStore CheckpointStore
 }
 
 // applyDefaults sets default values for unset Config fields.
@@ -106,6 +174,28 @@ func WithValidation(input, output bool) ConfigOption {
 	}
 }
 
+// WithSchemaDefaults enables or disables applying InputSchema defaults to
+// args before input validation.
+func WithSchemaDefaults(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.ApplySchemaDefaults = enabled
+	}
+}
+
+// WithCoercion sets the policy for coercing args to their schema types.
+func WithCoercion(policy CoercionPolicy) ConfigOption {
+	return func(c *Config) {
+		c.Coercion = policy
+	}
+}
+
+// WithSecretProvider sets the provider for secret references in args.
+func WithSecretProvider(p SecretProvider) ConfigOption {
+	return func(c *Config) {
+		c.Secrets = p
+	}
+}
+
 // WithBackendSelector sets a custom backend selector function.
 func WithBackendSelector(selector toolindex.BackendSelector) ConfigOption {
 	return func(c *Config) {
@@ -126,3 +216,87 @@ func WithBackendsResolver(resolver func(id string) ([]toolmodel.ToolBackend, err
 		c.BackendsResolver = resolver
 	}
 }
+
+// WithChainErrorPolicy sets the default error policy for chain steps.
+func WithChainErrorPolicy(policy ErrorPolicy) ConfigOption {
+	return func(c *Config) {
+		c.ChainErrorPolicy = policy
+	}
+}
+
+// WithCheckpointStore sets the store used to checkpoint and resume chains.
+func WithCheckpointStore(store CheckpointStore) ConfigOption {
+	return func(c *Config) {
+		c.CheckpointStore = store
+	}
+}
+
+// WithRetryPolicy sets the default retry policy for Run.
+func WithRetryPolicy(policy RetryPolicy) ConfigOption {
+	return func(c *Config) {
+		c.RetryPolicy = policy
+	}
+}
+
+// WithFailover sets the policy for failing over to alternate backends.
+func WithFailover(policy FailoverPolicy) ConfigOption {
+	return func(c *Config) {
+		c.Failover = policy
+	}
+}
+
+// WithCircuitBreakers sets the circuit breakers consulted before dispatch.
+func WithCircuitBreakers(b *CircuitBreakers) ConfigOption {
+	return func(c *Config) {
+		c.CircuitBreakers = b
+	}
+}
+
+// WithBackendObserver sets the observer of backend call outcomes.
+func WithBackendObserver(o BackendObserver) ConfigOption {
+	return func(c *Config) {
+		c.BackendObserver = o
+	}
+}
+
+// WithLimiter sets the limiter that bounds concurrent and per-period calls.
+func WithLimiter(l *Limiter) ConfigOption {
+	return func(c *Config) {
+		c.Limiter = l
+	}
+}
+
+// WithTimeouts sets the default execution timeouts for backend calls.
+func WithTimeouts(cfg TimeoutConfig) ConfigOption {
+	return func(c *Config) {
+		c.Timeouts = cfg
+	}
+}
+
+// WithInterceptors appends interceptors for all calls.
+func WithInterceptors(interceptors ...Interceptor) ConfigOption {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors appends interceptors for RunStream calls.
+func WithStreamInterceptors(interceptors ...StreamInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.StreamInterceptors = append(c.StreamInterceptors, interceptors...)
+	}
+}
+
+// WithHooks sets the phase hooks.
+func WithHooks(h Hooks) ConfigOption {
+	return func(c *Config) {
+		c.Hooks = h
+	}
+}
+
+// WithHedging sets the policy for hedging slow calls to alternate backends.
+func WithHedging(policy HedgePolicy) ConfigOption {
+	return func(c *Config) {
+		c.Hedging = policy
+	}
+}
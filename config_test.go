@@ -1,7 +1,9 @@
 package toolrun
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/jonwraymond/toolindex"
 	"github.com/jonwraymond/toolmodel"
@@ -235,3 +237,131 @@ func TestConfig_BackendSelector_MatchesToolindex(t *testing.T) {
 			ourResult.Kind, toolindexResult.Kind)
 	}
 }
+
+func TestWithChainErrorPolicy(t *testing.T) {
+	policy := ErrorPolicy{Action: ErrorActionFallback, FallbackToolID: "backup"}
+	runner := NewRunner(WithChainErrorPolicy(policy))
+
+	if runner.cfg.ChainErrorPolicy != policy {
+		t.Errorf("WithChainErrorPolicy() set %+v, want %+v", runner.cfg.ChainErrorPolicy, policy)
+	}
+}
+
+func TestWithCheckpointStore(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	runner := NewRunner(WithCheckpointStore(store))
+
+	if runner.cfg.CheckpointStore != store {
+		t.Error("WithCheckpointStore() did not set CheckpointStore")
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	runner := NewRunner(WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+
+	if runner.cfg.RetryPolicy.MaxAttempts != 3 {
+		t.Errorf("RetryPolicy.MaxAttempts = %d, want 3", runner.cfg.RetryPolicy.MaxAttempts)
+	}
+}
+
+func TestWithFailover(t *testing.T) {
+	runner := NewRunner(WithFailover(FailoverPolicy{Enabled: true}))
+
+	if !runner.cfg.Failover.Enabled {
+		t.Error("WithFailover() did not enable failover")
+	}
+}
+
+func TestWithCircuitBreakers(t *testing.T) {
+	breakers := NewCircuitBreakers(CircuitBreakerConfig{})
+	runner := NewRunner(WithCircuitBreakers(breakers))
+
+	if runner.cfg.CircuitBreakers != breakers {
+		t.Error("WithCircuitBreakers() did not set CircuitBreakers")
+	}
+}
+
+func TestWithLimiter(t *testing.T) {
+	limiter := NewLimiter(LimitConfig{})
+	runner := NewRunner(WithLimiter(limiter))
+
+	if runner.cfg.Limiter != limiter {
+		t.Error("WithLimiter() did not set Limiter")
+	}
+}
+
+func TestWithHedging(t *testing.T) {
+	runner := NewRunner(WithHedging(HedgePolicy{Delay: time.Second}))
+
+	if runner.cfg.Hedging.Delay != time.Second {
+		t.Errorf("Hedging.Delay = %v, want 1s", runner.cfg.Hedging.Delay)
+	}
+}
+
+func TestWithBackendObserver(t *testing.T) {
+	sel := NewAdaptiveSelector(AdaptiveSelectorConfig{})
+	runner := NewRunner(WithBackendObserver(sel))
+
+	if runner.cfg.BackendObserver != sel {
+		t.Error("WithBackendObserver() did not set BackendObserver")
+	}
+}
+
+func TestWithTimeouts(t *testing.T) {
+	runner := NewRunner(WithTimeouts(TimeoutConfig{MCP: time.Second}))
+
+	if runner.cfg.Timeouts.MCP != time.Second {
+		t.Errorf("Timeouts.MCP = %v, want 1s", runner.cfg.Timeouts.MCP)
+	}
+}
+
+func TestWithInterceptors(t *testing.T) {
+	ic := func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+		return next(ctx, call)
+	}
+	sic := func(ctx context.Context, call *Call, next StreamHandler) (<-chan StreamEvent, error) {
+		return next(ctx, call)
+	}
+	runner := NewRunner(WithInterceptors(ic, ic), WithInterceptors(ic), WithStreamInterceptors(sic))
+
+	if len(runner.cfg.Interceptors) != 3 {
+		t.Errorf("len(Interceptors) = %d, want 3", len(runner.cfg.Interceptors))
+	}
+	if len(runner.cfg.StreamInterceptors) != 1 {
+		t.Errorf("len(StreamInterceptors) = %d, want 1", len(runner.cfg.StreamInterceptors))
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	hook := func(_ context.Context, call Call) (map[string]any, error) { return call.Args, nil }
+	runner := NewRunner(WithHooks(Hooks{BeforeDispatch: hook}))
+
+	if runner.cfg.Hooks.BeforeDispatch == nil {
+		t.Error("WithHooks() did not set Hooks")
+	}
+}
+
+func TestWithSchemaDefaults(t *testing.T) {
+	runner := NewRunner(WithSchemaDefaults(true))
+
+	if !runner.cfg.ApplySchemaDefaults {
+		t.Error("WithSchemaDefaults(true) did not enable ApplySchemaDefaults")
+	}
+}
+
+func TestWithCoercion(t *testing.T) {
+	runner := NewRunner(WithCoercion(CoercionPolicy{Enabled: true, Lenient: true}))
+
+	if !runner.cfg.Coercion.Enabled || !runner.cfg.Coercion.Lenient {
+		t.Errorf("Coercion = %+v, want enabled and lenient", runner.cfg.Coercion)
+	}
+}
+
+func TestWithSecretProvider(t *testing.T) {
+	p := EnvSecretProvider{Prefix: "APP_"}
+	runner := NewRunner(WithSecretProvider(p))
+
+	if runner.cfg.Secrets != p {
+		t.Errorf("Secrets = %v, want %v", runner.cfg.Secrets, p)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jonwraymond/toolmodel"
 )
@@ -30,58 +31,186 @@ func NewRunner(opts ...ConfigOption) *DefaultRunner {
 }
 
 // Run executes a single tool and returns the normalized result.
+// Failed attempts are retried according to the retry policy
+// (see Config.RetryPolicy and WithCallRetryPolicy).
 func (r *DefaultRunner) Run(ctx context.Context, toolID string, args map[string]any) (RunResult, error) {
+	return r.run(ctx, toolID, args, nil)
+}
+
+// run implements Run, notifying onRetry (if non-nil) before each retry.
+func (r *DefaultRunner) run(ctx context.Context, toolID string, args map[string]any, onRetry retryFunc) (RunResult, error) {
+	// 1-2. Resolve, select backends
+	call, backends, err := r.resolveCall(ctx, toolID, args)
+	if err != nil {
+		return r.intercept(ctx, unresolvedCall(toolID, args), failedRun(err))
+	}
+	return r.intercept(ctx, call, func(ctx context.Context, call *Call) (RunResult, error) {
+		opts := dispatchOptions{secrets: r.newSecretCache()}
+		// 3. Validate input
+		adm, err := r.admit(ctx, call, backends, opts.secrets)
+		if err != nil {
+			return RunResult{}, err
+		}
+		result, err := r.runPrepared(ctx, call.ToolID, call.Tool, adm.backends, adm.args, onRetry, opts)
+		result.Coercions = adm.coercions
+		return result, err
+	})
+}
+
+// dispatchOptions holds the settings shared by every backend call made for
+// one tool call.
+type dispatchOptions struct {
+	// sink, when set, receives the chunk and progress events of backends
+	// that stream (see dispatchStreamed).
+	sink *chainSink
+
+	// secrets caches the call's secret lookups across validation, retries,
+	// and failover.
+	secrets *secretCache
+}
+
+// runPrepared dispatches a prepared call with retries and failover.
+func (r *DefaultRunner) runPrepared(ctx context.Context, toolID string, tool toolmodel.Tool, backends []toolmodel.ToolBackend, args map[string]any, onRetry retryFunc, opts dispatchOptions) (RunResult, error) {
+	var tried []toolmodel.ToolBackend
+	result, err := withRetries(ctx, r.retryPolicy(ctx), onRetry, func() (RunResult, error) {
+		return r.runBackends(ctx, toolID, tool, backends, args, &tried, opts)
+	})
+	recordTried(&result, err, tried)
+	return result, err
+}
+
+// resolveCall resolves a tool and orders its backends, describing the call
+// for interceptors. The first backend is the selected one; alternates follow
+// only when failover or hedging is enabled. Errors other than context errors
+// are returned as ToolErrors.
+func (r *DefaultRunner) resolveCall(ctx context.Context, toolID string, args map[string]any) (*Call, []toolmodel.ToolBackend, error) {
 	if err := ctx.Err(); err != nil {
-		return RunResult{}, err
+		return nil, nil, err
 	}
 	if toolID == "" {
-		return RunResult{}, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
+		return nil, nil, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
 	}
 	// 1. Resolve tool + backends
 	resolved, err := r.resolveTool(ctx, toolID)
 	if err != nil {
-		return RunResult{}, WrapError(toolID, nil, "resolve", err)
+		return nil, nil, WrapError(toolID, nil, "resolve", err)
+	}
+
+	tool, backends := resolved.tool, resolved.backends
+	if hook := r.cfg.Hooks.OnResolved; hook != nil {
+		if tool, backends, err = hook(ctx, toolID, tool, backends); err != nil {
+			return nil, nil, hookError(Call{ToolID: toolID}, err)
+		}
 	}
 
 	// 2. Select backend
-	backend, err := r.selectBackend(resolved.backends)
+	backends, err = r.orderBackends(tool, backends)
 	if err != nil {
-		return RunResult{}, WrapError(toolID, nil, "select_backend", err)
+		return nil, nil, WrapError(toolID, nil, "select_backend", err)
+	}
+	call := &Call{ToolID: toolID, Args: args, Tool: tool, Backend: backends[0]}
+	if hook := r.cfg.Hooks.OnBackendSelected; hook != nil {
+		backend, err := hook(ctx, *call)
+		if err != nil {
+			return nil, nil, hookError(*call, err)
+		}
+		call.Backend = backend
+	}
+	return call, backends, nil
+}
+
+// admission is a call admitted for dispatch.
+type admission struct {
+	// backends are the backends to dispatch to, the selected one first.
+	backends []toolmodel.ToolBackend
+
+	// args are the args to dispatch.
+	args map[string]any
+
+	// coercions are the coercions applied to the args.
+	coercions []Coercion
+}
+
+// admit applies the call's choice of backend, schema defaults, and
+// coercions, and validates its input. Secrets are looked up through the
+// call's cache.
+func (r *DefaultRunner) admit(ctx context.Context, call *Call, backends []toolmodel.ToolBackend, secrets *secretCache) (admission, error) {
+	adm := admission{backends: preferBackend(backends, call.Backend)}
+	c := *call
+	if r.cfg.ApplySchemaDefaults {
+		c.Args = applySchemaDefaults(c.Tool.InputSchema, c.Args)
+	}
+	if r.cfg.Coercion.Enabled {
+		c.Args, adm.coercions = coerceArgs(c.Tool.InputSchema, c.Args, r.cfg.Coercion.Lenient)
 	}
 
 	// 3. Validate input
 	if r.cfg.ValidateInput {
-		if err := r.cfg.Validator.ValidateInput(&resolved.tool, args); err != nil {
-			return RunResult{}, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
+		// Secret references are validated as the values they resolve to.
+		args, red, err := r.resolveSecrets(ctx, c, secrets)
+		if err != nil {
+			return admission{}, err
+		}
+		if err := r.cfg.Validator.ValidateInput(&c.Tool, args); err != nil {
+			err = WrapError(c.ToolID, &adm.backends[0], "validate_input", fmt.Errorf("%w: %s", ErrValidation, red.string(err.Error())))
+			if err := r.validationFailed(ctx, c, err); err != nil {
+				return admission{}, err
+			}
 		}
 	}
-
-	// 4. Dispatch
-	dispatchResult, err := r.dispatch(ctx, resolved.tool, backend, args)
-	if err != nil {
-		return RunResult{}, WrapError(toolID, &backend, "execute", fmt.Errorf("%w: %v", ErrExecution, err))
+	adm.args = c.Args
+	if hook := r.cfg.Hooks.AfterValidateInput; hook != nil {
+		args, err := hook(ctx, c)
+		if err != nil {
+			return admission{}, hookError(c, err)
+		}
+		adm.args = args
 	}
+	return adm, nil
+}
 
+// finish normalizes a dispatch result and validates the output.
+func (r *DefaultRunner) finish(ctx context.Context, call Call, dr *dispatchResult) (RunResult, error) {
 	// 5. Normalize
-	result := r.normalize(resolved.tool, backend, dispatchResult)
+	result := r.normalize(call.Tool, call.Backend, dr)
+	if hook := r.cfg.Hooks.AfterNormalize; hook != nil {
+		var err error
+		if result, err = hook(ctx, call, result); err != nil {
+			return RunResult{}, hookError(call, err)
+		}
+	}
 
 	// 6. Validate output
 	if r.cfg.ValidateOutput {
-		if err := r.cfg.Validator.ValidateOutput(&resolved.tool, result.Structured); err != nil {
-			return RunResult{}, WrapError(toolID, &backend, "validate_output", fmt.Errorf("%w: %v", ErrOutputValidation, err))
+		if err := r.cfg.Validator.ValidateOutput(&call.Tool, result.Structured); err != nil {
+			err = WrapError(call.ToolID, &call.Backend, "validate_output", fmt.Errorf("%w: %v", ErrOutputValidation, err))
+			if err := r.validationFailed(ctx, call, err); err != nil {
+				return RunResult{}, err
+			}
 		}
 	}
 
 	return result, nil
 }
 
-// RunWithProgress executes a tool and emits coarse progress updates.
+// RunWithProgress executes a tool and emits coarse progress updates,
+// including one event per retry.
 func (r *DefaultRunner) RunWithProgress(ctx context.Context, toolID string, args map[string]any, onProgress ProgressCallback) (RunResult, error) {
 	if onProgress != nil {
 		onProgress(ProgressEvent{Progress: 0, Total: 1, Message: "started"})
 	}
 
-	result, err := r.Run(ctx, toolID, args)
+	var onRetry retryFunc
+	if onProgress != nil {
+		onRetry = func(attempt int, err error, delay time.Duration) {
+			onProgress(ProgressEvent{
+				Progress: 0,
+				Total:    1,
+				Message:  fmt.Sprintf("retry %d after %s: %v", attempt, delay.Round(time.Millisecond), err),
+			})
+		}
+	}
+	result, err := r.run(ctx, toolID, args, onRetry)
 
 	if onProgress != nil {
 		msg := "completed"
@@ -96,37 +225,53 @@ func (r *DefaultRunner) RunWithProgress(ctx context.Context, toolID string, args
 
 // RunStream executes a tool with streaming support.
 func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[string]any) (<-chan StreamEvent, error) {
-	if err := ctx.Err(); err != nil {
-		return nil, err
-	}
-	if toolID == "" {
-		return nil, WrapError(toolID, nil, "validate_tool_id", ErrInvalidToolID)
-	}
-	// 1. Resolve tool + backends
-	resolved, err := r.resolveTool(ctx, toolID)
+	// 1-2. Resolve, select backend
+	call, backends, err := r.resolveCall(ctx, toolID, args)
 	if err != nil {
-		return nil, WrapError(toolID, nil, "resolve", err)
+		return r.interceptStream(ctx, unresolvedCall(toolID, args), func(ctx context.Context, call *Call) (<-chan StreamEvent, error) {
+			return r.interceptStreamStart(ctx, call, func(context.Context, *Call) (<-chan StreamEvent, error) {
+				return nil, err
+			})
+		})
 	}
+	return r.interceptStream(ctx, call, func(ctx context.Context, call *Call) (<-chan StreamEvent, error) {
+		return r.interceptStreamStart(ctx, call, func(ctx context.Context, call *Call) (<-chan StreamEvent, error) {
+			secrets := r.newSecretCache()
+			// 3. Validate input
+			adm, err := r.admit(ctx, call, backends, secrets)
+			if err != nil {
+				return nil, err
+			}
+			return r.runStream(ctx, call.ToolID, call.Tool, adm.backends[0], adm.args, secrets)
+		})
+	})
+}
 
-	// 2. Select backend
-	backend, err := r.selectBackend(resolved.backends)
+// runStream dispatches a validated streaming call.
+func (r *DefaultRunner) runStream(ctx context.Context, toolID string, tool toolmodel.Tool, backend toolmodel.ToolBackend, args map[string]any, secrets *secretCache) (<-chan StreamEvent, error) {
+	// 4. Dispatch stream
+	call := Call{ToolID: toolID, Args: args, Tool: tool, Backend: backend}
+	args, err := r.beforeDispatch(ctx, call)
 	if err != nil {
-		return nil, WrapError(toolID, nil, "select_backend", err)
+		return nil, err
 	}
-
-	// 3. Validate input
-	if r.cfg.ValidateInput {
-		if err := r.cfg.Validator.ValidateInput(&resolved.tool, args); err != nil {
-			return nil, WrapError(toolID, &backend, "validate_input", fmt.Errorf("%w: %v", ErrValidation, err))
-		}
+	call.Args = args
+	args, red, err := r.resolveSecrets(ctx, call, secrets)
+	if err != nil {
+		return nil, err
 	}
-
-	// 4. Dispatch stream
-	rawChan, err := r.dispatchStream(ctx, resolved.tool, backend, args)
+	// The limiter's slots are held until the stream ends.
+	release, err := r.cfg.Limiter.acquire(ctx, tool, backend)
 	if err != nil {
-		return nil, WrapError(toolID, &backend, "stream", err)
+		return nil, WrapError(toolID, &backend, "limit", err)
+	}
+	rawChan, err := r.dispatchStream(ctx, tool, backend, args)
+	if err != nil {
+		release()
+		return nil, WrapError(toolID, &backend, "stream", red.err(err))
 	}
 	if rawChan == nil {
+		release()
 		// Guard against executors returning (nil, nil), which would hang callers.
 		return nil, WrapError(toolID, &backend, "stream", ErrStreamNotSupported)
 	}
@@ -135,6 +280,7 @@ func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[s
 	out := make(chan StreamEvent)
 	go func() {
 		defer close(out)
+		defer release()
 		for {
 			select {
 			case <-ctx.Done():
@@ -146,6 +292,7 @@ func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[s
 				if ev.ToolID == "" {
 					ev.ToolID = toolID
 				}
+				ev = red.event(ev)
 				select {
 				case out <- ev:
 				case <-ctx.Done():
@@ -159,12 +306,12 @@ func (r *DefaultRunner) RunStream(ctx context.Context, toolID string, args map[s
 
 // RunChain executes a sequence of tool steps.
 func (r *DefaultRunner) RunChain(ctx context.Context, steps []ChainStep) (RunResult, []StepResult, error) {
-	return r.runChainWithProgress(ctx, steps, nil)
+	return r.runChainWithProgress(withChainSink(ctx, nil), steps, nil)
 }
 
 // RunChainWithProgress executes a chain and emits progress updates.
 func (r *DefaultRunner) RunChainWithProgress(ctx context.Context, steps []ChainStep, onProgress ProgressCallback) (RunResult, []StepResult, error) {
-	return r.runChainWithProgress(ctx, steps, onProgress)
+	return r.runChainWithProgress(withChainSink(ctx, nil), steps, onProgress)
 }
 
 func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainStep, onProgress ProgressCallback) (RunResult, []StepResult, error) {
@@ -176,42 +323,62 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 		onProgress(ProgressEvent{Progress: 0, Total: float64(len(steps)), Message: "started"})
 	}
 
-	var results []StepResult
-	var previous any
+	if err := r.checkChain(steps); err != nil {
+		return RunResult{}, nil, err
+	}
+
+	inputs := chainInputs(ctx)
+	var cp *Checkpoint
+	if runID := chainRunID(ctx); runID != "" && r.cfg.CheckpointStore != nil {
+		cp = &Checkpoint{RunID: runID, Steps: steps, Inputs: inputs, Deadline: chainDeadline(ctx)}
+		if err := r.saveCheckpoint(ctx, cp); err != nil {
+			return RunResult{}, nil, err
+		}
+	}
+
+	return r.continueChain(ctx, steps, nil, inputs, cp, onProgress)
+}
 
-	for i, step := range steps {
+// continueChain runs steps[len(completed):], treating completed as the
+// results of the steps before it. If cp is non-nil, the checkpoint is saved
+// after each completed step and once more when the chain finishes.
+func (r *DefaultRunner) continueChain(ctx context.Context, steps []ChainStep, completed []StepResult, inputs map[string]any, cp *Checkpoint, onProgress ProgressCallback) (RunResult, []StepResult, error) {
+	ctx, cancel := chainContext(ctx, cp)
+	defer cancel()
+
+	results := append([]StepResult(nil), completed...)
+	sink := chainSinkFrom(ctx)
+	var final RunResult
+	scope := &chainScope{inputs: inputs}
+	for _, sr := range completed {
+		final = scope.advance(sr, final)
+	}
+
+	for i := len(completed); i < len(steps); i++ {
+		step := steps[i]
 		if err := ctx.Err(); err != nil {
-			return RunResult{}, results, err
-		}
-		// Build args with previous injection
-		args := r.buildChainArgs(step, previous)
-
-		// Execute the step
-		result, err := r.Run(ctx, step.ToolID, args)
-
-		// Resolve backend for StepResult (we need to resolve again to get it)
-		var backend toolmodel.ToolBackend
-		if err == nil {
-			backend = result.Backend
-		} else {
-			var toolErr *ToolError
-			if errors.As(err, &toolErr) && toolErr.Backend != nil {
-				backend = *toolErr.Backend
+			if cause := timeoutCause(ctx); cause != nil {
+				err = timeoutError(step, toolmodel.ToolBackend{}, cause)
 			}
+			return RunResult{}, results, r.abortChain(ctx, steps, results, scope, cp, err)
 		}
-
-		stepResult := StepResult{
-			ToolID:  step.ToolID,
-			Backend: backend,
-			Result:  result,
-			Err:     err,
+		stepCtx := ctx
+		if sink != nil {
+			stepCtx = withChainSink(ctx, sink.forStep(i))
+			sink.forStep(i).emit(StreamEvent{Kind: StreamEventStepStarted, ToolID: step.ToolID})
 		}
+		stepResult, abortErr := r.runChainStep(stepCtx, step, scope)
 		results = append(results, stepResult)
+		if sink != nil {
+			sink.forStep(i).stepDone(stepResult)
+		}
 
 		if onProgress != nil {
 			msg := "step_completed"
-			if err != nil {
+			if stepResult.Err != nil {
 				msg = "step_error"
+			} else if stepResult.Skipped {
+				msg = "step_skipped"
 			}
 			onProgress(ProgressEvent{
 				Progress: float64(i + 1),
@@ -220,31 +387,135 @@ func (r *DefaultRunner) runChainWithProgress(ctx context.Context, steps []ChainS
 			})
 		}
 
-		// Stop on first error (v1 policy)
-		if err != nil {
-			return RunResult{}, results, err
+		// Stop unless the step's error policy handled the failure
+		if abortErr != nil {
+			return RunResult{}, results, r.abortChain(ctx, steps, results, scope, cp, abortErr)
+		}
+
+		final = scope.advance(stepResult, final)
+		if cp != nil {
+			cp.Results = results
+			cp.Done = i == len(steps)-1
+			if err := r.saveCheckpoint(ctx, cp); err != nil {
+				return RunResult{}, results, err
+			}
+		}
+	}
+
+	// Return the last executed step's result
+	return final, results, nil
+}
+
+// abortChain runs the compensations of completed steps after the chain
+// aborted with cause. Compensated steps are dropped from the checkpoint so
+// that a resumed run executes them again.
+func (r *DefaultRunner) abortChain(ctx context.Context, steps []ChainStep, results []StepResult, scope *chainScope, cp *Checkpoint, cause error) error {
+	undone, err := r.compensateChain(ctx, steps, results, scope, cause)
+	if cp != nil && undone < len(cp.Results) {
+		cp.Results = cp.Results[:undone]
+		cp.Done = false
+		if saveErr := r.saveCheckpoint(context.WithoutCancel(ctx), cp); saveErr != nil {
+			err = errors.Join(err, saveErr)
 		}
+	}
+	return err
+}
+
+// runStep executes a single chain step and captures its outcome, along with
+// the args it was dispatched with (nil if they could not be built).
+func (r *DefaultRunner) runStep(ctx context.Context, step ChainStep, scope *chainScope) (StepResult, map[string]any) {
+	if step.ForEach != "" {
+		return r.runForEach(ctx, step, scope)
+	}
+
+	// Build args with references resolved and previous injected
+	args, err := r.buildChainArgs(step, scope)
+	if err != nil {
+		return StepResult{
+			ID:     step.ID,
+			ToolID: step.ToolID,
+			Err:    WrapError(step.ToolID, nil, "resolve_args", err),
+		}, nil
+	}
+
+	// Execute the step
+	result, err := r.execute(ctx, step.ToolID, args)
+
+	return StepResult{
+		ID:            step.ID,
+		ToolID:        step.ToolID,
+		Backend:       stepBackend(result, err),
+		BackendsTried: stepTried(result, err),
+		Result:        result,
+		Err:           err,
+	}, args
+}
+
+// stepBackend returns the backend used for a run: from the result on
+// success, or from the ToolError when the failure happened after backend
+// selection.
+func stepBackend(result RunResult, err error) toolmodel.ToolBackend {
+	if err == nil {
+		return result.Backend
+	}
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) && toolErr.Backend != nil {
+		return *toolErr.Backend
+	}
+	return toolmodel.ToolBackend{}
+}
 
-		// Update previous for next step
-		previous = result.Structured
+// checkChain validates a chain before any step runs: it applies
+// validateChainShape and checks the runner's error policy for the steps
+// without their own.
+func (r *DefaultRunner) checkChain(steps []ChainStep) error {
+	if err := validateChainShape(steps); err != nil {
+		return err
 	}
+	// Steps without a policy of their own use the runner's.
+	for _, step := range steps {
+		if step.OnError == nil {
+			if err := checkErrorPolicy(&r.cfg.ChainErrorPolicy); err != nil {
+				return WrapError(step.ToolID, nil, "check_chain", err)
+			}
+			break
+		}
+	}
+	return nil
+}
 
-	// Return the last successful result
-	lastResult := results[len(results)-1].Result
-	return lastResult, results, nil
+// validateChainShape checks the parts of a chain that do not depend on a
+// runner: each step's own error policy, and its references, When condition,
+// ForEach path, and compensation.
+func validateChainShape(steps []ChainStep) error {
+	for _, step := range steps {
+		if err := checkErrorPolicy(step.OnError); err != nil {
+			return WrapError(step.ToolID, nil, "check_chain", err)
+		}
+	}
+	return checkChainRefs(steps)
 }
 
 // buildChainArgs builds the args map for a chain step.
-// If UsePrevious is true, injects previous result at args["previous"].
-func (r *DefaultRunner) buildChainArgs(step ChainStep, previous any) map[string]any {
-	args := make(map[string]any)
-	for k, v := range step.Args {
-		args[k] = v
+// References in step.Args are resolved against scope, and if UsePrevious is
+// true, the previous result is injected at args["previous"]. Projected args
+// are selected from the previous result last.
+func (r *DefaultRunner) buildChainArgs(step ChainStep, scope *chainScope) (map[string]any, error) {
+	resolved, err := resolveRefs(step.Args, scope)
+	if err != nil {
+		return nil, err
+	}
+	args, _ := resolved.(map[string]any)
+	if args == nil {
+		args = make(map[string]any)
 	}
 	if step.UsePrevious {
-		args["previous"] = previous
+		args["previous"] = scope.previous
+	}
+	if err := project(step, scope.previous, args); err != nil {
+		return nil, err
 	}
-	return args
+	return args, nil
 }
 
 // Ensure DefaultRunner implements Runner.
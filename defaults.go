@@ -0,0 +1,86 @@
+package toolrun
+
+import (
+	"maps"
+	"slices"
+)
+
+// applySchemaDefaults returns args with the "default" values of schema
+// applied: properties missing from an object are set to their default, and
+// defaults are applied recursively to nested objects and to the items of
+// arrays. args is never modified; changed maps and slices are copied. Nil
+// args are treated as an empty object.
+func applySchemaDefaults(schema any, args map[string]any) map[string]any {
+	s, ok := schemaObject(schema)
+	if !ok {
+		return args
+	}
+	var value any = args
+	if args == nil {
+		value = map[string]any{}
+	}
+	out, changed := withDefaults(s, value)
+	if !changed {
+		return args
+	}
+	return out.(map[string]any)
+}
+
+// withDefaults applies the defaults of schema to v, reporting whether the
+// returned value differs from v.
+func withDefaults(schema map[string]any, v any) (any, bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		var out map[string]any
+		for name, p := range props {
+			prop, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+			var next any
+			var changed bool
+			if cur, present := val[name]; present {
+				next, changed = withDefaults(prop, cur)
+			} else if def, ok := prop["default"]; ok {
+				// Defaults come from a freshly decoded schema, so they are
+				// not shared with the tool definition.
+				next, _ = withDefaults(prop, def)
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = maps.Clone(val)
+			}
+			out[name] = next
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+
+	case []any:
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			return v, false
+		}
+		var out []any
+		for i, item := range val {
+			next, changed := withDefaults(items, item)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(val)
+			}
+			out[i] = next
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	}
+	return v, false
+}
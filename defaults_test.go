@@ -0,0 +1,142 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func defaultsTestSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit": map[string]any{"type": "integer", "default": 10},
+			"query": map[string]any{"type": "string"},
+			"options": map[string]any{
+				"type":    "object",
+				"default": map[string]any{},
+				"properties": map[string]any{
+					"sort": map[string]any{"type": "string", "default": "asc"},
+				},
+			},
+			"filters": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"op": map[string]any{"type": "string", "default": "eq"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestApplySchemaDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+	}{
+		{
+			name: "nil args",
+			args: nil,
+			want: map[string]any{"limit": 10.0, "options": map[string]any{"sort": "asc"}},
+		},
+		{
+			name: "present values kept",
+			args: map[string]any{"limit": 3, "query": "q"},
+			want: map[string]any{"limit": 3, "query": "q", "options": map[string]any{"sort": "asc"}},
+		},
+		{
+			name: "nested object",
+			args: map[string]any{"options": map[string]any{}},
+			want: map[string]any{"limit": 10.0, "options": map[string]any{"sort": "asc"}},
+		},
+		{
+			name: "array items",
+			args: map[string]any{"filters": []any{map[string]any{}, map[string]any{"op": "ne"}}},
+			want: map[string]any{
+				"limit":   10.0,
+				"options": map[string]any{"sort": "asc"},
+				"filters": []any{map[string]any{"op": "eq"}, map[string]any{"op": "ne"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applySchemaDefaults(defaultsTestSchema(), tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applySchemaDefaults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySchemaDefaults_DoesNotModifyArgs(t *testing.T) {
+	args := map[string]any{
+		"options": map[string]any{},
+		"filters": []any{map[string]any{}},
+	}
+	before, _ := json.Marshal(args)
+
+	applySchemaDefaults(defaultsTestSchema(), args)
+
+	after, _ := json.Marshal(args)
+	if string(before) != string(after) {
+		t.Errorf("args changed from %s to %s", before, after)
+	}
+}
+
+func TestApplySchemaDefaults_Unchanged(t *testing.T) {
+	args := map[string]any{"a": 1}
+	schema := map[string]any{"type": "object", "properties": map[string]any{"a": map[string]any{"type": "integer"}}}
+
+	if got := applySchemaDefaults(schema, args); reflect.ValueOf(got).Pointer() != reflect.ValueOf(args).Pointer() {
+		t.Error("args without applicable defaults should not be copied")
+	}
+	if got := applySchemaDefaults(nil, args); !reflect.DeepEqual(got, args) {
+		t.Errorf("applySchemaDefaults(nil) = %v, want args", got)
+	}
+}
+
+func TestRun_SchemaDefaults(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		idx := newMockIndex()
+		tool := testTool("search")
+		tool.InputSchema = map[string]any{
+			"type":     "object",
+			"required": []any{"limit"},
+			"properties": map[string]any{
+				"limit": map[string]any{"type": "integer", "default": 10},
+			},
+		}
+		mustRegisterTool(t, idx, tool, testLocalBackend("search"))
+		var got map[string]any
+		localReg := newMockLocalRegistry()
+		localReg.Register("search", func(_ context.Context, args map[string]any) (any, error) {
+			got = args
+			return nil, nil
+		})
+		runner := NewRunner(
+			WithIndex(idx),
+			WithLocalRegistry(localReg),
+			WithValidation(true, false),
+			WithSchemaDefaults(enabled),
+		)
+
+		args := map[string]any{}
+		_, err := runner.Run(context.Background(), "search", args)
+		if enabled {
+			if err != nil || got["limit"] != 10.0 {
+				t.Errorf("enabled: Run() = %v, %v; want the default limit applied", got, err)
+			}
+			if len(args) != 0 {
+				t.Errorf("enabled: caller's args = %v, want untouched", args)
+			}
+		} else if err == nil {
+			t.Error("disabled: Run() should fail validation without the default")
+		}
+	}
+}
@@ -17,9 +17,14 @@ type dispatchResult struct {
 
 	// mcpResult is the raw MCP result when the backend was MCP.
 	mcpResult *mcp.CallToolResult
+
+	// steps are the nested step results when the handler was a composite tool.
+	steps []StepResult
 }
 
-// dispatch executes a tool via the appropriate backend.
+// dispatch executes a tool via the appropriate backend. When a composite
+// tool fails, the result is returned along with the error and holds the
+// steps its chain ran.
 func (r *DefaultRunner) dispatch(ctx context.Context, tool toolmodel.Tool, backend toolmodel.ToolBackend, args map[string]any) (*dispatchResult, error) {
 	switch backend.Kind {
 	case toolmodel.BackendKindMCP:
@@ -94,12 +99,17 @@ func (r *DefaultRunner) dispatchLocal(ctx context.Context, _ toolmodel.Tool, bac
 		return nil, fmt.Errorf("local handler %q not found", backend.Local.Name)
 	}
 
+	ctx, nested := withNestedSteps(ctx)
 	result, err := handler(ctx, args)
 	if err != nil {
+		if steps := nested.get(); steps != nil {
+			return &dispatchResult{steps: steps}, err
+		}
 		return nil, err
 	}
 
 	return &dispatchResult{
 		structured: result,
+		steps:      nested.get(),
 	}, nil
 }
@@ -30,13 +30,160 @@
 // Input validation is performed before execution using toolmodel.SchemaValidator.
 // Output validation is performed after execution when tool.OutputSchema is present.
 // Both can be configured via ValidateInput and ValidateOutput options.
+// With WithSchemaDefaults, the "default" values of the InputSchema are
+// applied recursively to a copy of the args before validation. A
+// CoercionPolicy (see WithCoercion) then converts values the schema does not
+// allow, such as "5" for an integer, recording each change in
+// RunResult.Coercions.
+//
+// # Interceptors
+//
+// Interceptors (see WithInterceptors) wrap every tool call: Run, RunStream,
+// the progress variants, and the calls made by chain, graph, and chain stream
+// steps. They run after resolution and backend selection, see the call's tool
+// ID, args, resolved tool, and selected backend, and may change the args or
+// backend, short-circuit the call, or observe and replace its result. Changed
+// args are validated as usual. The first interceptor is outermost. Failed
+// calls reach interceptors too: a call whose tool fails to resolve is passed
+// with a zero tool and backend. StreamInterceptors (see
+// WithStreamInterceptors) additionally wrap RunStream, outside Interceptors,
+// to see or wrap its event channel.
+//
+// # Hooks
+//
+// Hooks (see WithHooks) are typed callbacks for the phases of a call:
+// OnResolved, OnBackendSelected, AfterValidateInput, OnValidationFailure,
+// BeforeDispatch, and AfterNormalize. Each may inspect its phase's data and
+// return a replacement, for example to override the selected backend or
+// rewrite args after validation, or fail the call with a ToolError whose Op
+// is "hook".
+//
+// # Secrets
+//
+// With a SecretProvider (see WithSecretProvider), args may hold secret
+// references such as {"$secret": "github/token"}. They are resolved just
+// before each backend call, so interceptors, hooks, and chain documents only
+// see the references; each name is looked up once per call and reused across
+// validation, retries, and failover. Resolved values are replaced with
+// Redacted in ToolError messages, results, step results, and stream events.
+// EnvSecretProvider and FileSecretProvider read secrets from environment
+// variables and mounted files.
+//
+// # Resilience
+//
+// Run retries failed attempts according to a RetryPolicy, set runner-wide with
+// WithRetryPolicy or per call with WithCallRetryPolicy. Delays grow
+// exponentially with optional jitter; DefaultRetryable retries execution
+// failures and never validation errors. RunResult.Attempts reports the number
+// of attempts made, and RunWithProgress emits an event per retry.
+//
+// With failover enabled (see WithFailover), a failed call moves on to the
+// tool's alternate backends in BackendSelector preference order.
+// RunResult.BackendsTried, StepResult.BackendsTried, and
+// ToolError.BackendsTried record every backend a call was dispatched to.
+//
+// CircuitBreakers (see WithCircuitBreakers) track a breaker per backend
+// instance: an MCP server, a provider, or a local handler. After repeated
+// failures a circuit opens; its backend is skipped in favor of alternates,
+// and calls to it fail fast with ErrCircuitOpen until a probe call succeeds
+// after the cooldown.
+//
+// A Limiter (see WithLimiter) enforces concurrency limits (bulkheads) and
+// token-bucket rate limits per tool ID, namespace, MCP server, and provider.
+// Calls over a limit wait for their turn or, in LimitReject mode, fail
+// immediately with a LimitError matching ErrLimitExceeded.
+//
+// TimeoutConfig (see WithTimeouts) sets default execution timeouts per
+// backend kind, with per-tool overrides by tool ID or pattern such as
+// "github:*". A call ended by the runner's own timeout, rather than the
+// caller's deadline, fails with op "timeout" and ErrCallTimeout, which
+// DefaultRetryable retries.
+//
+// With hedging enabled (see WithHedging), a call to a tool annotated as
+// idempotent or read-only is also sent to the next backend when the selected
+// one has not answered within the hedge delay; the first success wins and the
+// other calls are canceled.
+//
+// An AdaptiveSelector replaces the fixed local > provider > mcp order with
+// one learned from real calls: installed with both WithBackendSelector and
+// WithBackendObserver, it tracks each backend's success rate and latency as
+// moving averages and prefers healthy, fast backends, scaled by optional
+// static weights. Its Stats method exposes what it has learned.
 //
 // # Chains
 //
 // Chains execute steps sequentially with explicit data passing.
 // If UsePrevious is true, the prior step's structured result is injected
 // at args["previous"] (overwriting any existing value).
-// Chains stop on first error (v1 policy).
+// By default chains stop on the first error. An ErrorPolicy, set per step via
+// ChainStep.OnError or per runner via Config.ChainErrorPolicy, can instead
+// continue past the failure or run a fallback tool; StepResult.Policy records
+// which policy fired.
+//
+// Step args may reference earlier results with path expressions, either as a
+// whole-string template "{{steps.fetch.structured.items[0].id}}" or a string
+// starting with "$." such as "$.steps[1].result". Steps are addressed by ID or
+// index; "previous" addresses the value UsePrevious would inject, and "inputs"
+// addresses values attached with WithChainInputs. References are checked
+// before the chain starts and resolved before each step dispatches. A leading
+// backslash escapes a literal string that would otherwise be a reference:
+// `\$.50` is passed as "$.50".
+//
+// A step's Project map selects fields of the previous result into named
+// args, using paths relative to that result or JSON pointers, so that large
+// outputs need not be passed whole at args["previous"].
+//
+// A step's When condition (for example `previous.status == "needs_review"`)
+// is evaluated against the same roots; when false the step is skipped and
+// recorded with StepResult.Skipped.
+//
+// A step with ForEach is a map step: it calls its tool once per element of an
+// array selected from an earlier result, with bounded Concurrency, and
+// collects the outputs into an array. Each call is recorded in
+// StepResult.Iterations.
+//
+// A step may declare a Compensate tool call that undoes its effects. When a
+// chain aborts, the compensations of steps that succeeded run in reverse
+// order and are recorded in StepResult.Compensation.
+//
+// A step's Timeout bounds the step's whole run, including retries and all
+// ForEach iterations but not a fallback tool, and WithChainTimeout bounds the
+// whole chain. Step timeouts fail the step with ErrStepTimeout and are
+// handled by ErrorPolicy.OnTimeout when set, else by the step's policy; a
+// chain timeout aborts the chain with ErrChainTimeout. Both errors also match
+// context.DeadlineExceeded.
+//
+// With Config.CheckpointStore set, chains run under a run ID (see WithRunID)
+// save a Checkpoint after each completed step. ResumeChain continues such a
+// run from its first incomplete step, reusing the recorded results instead of
+// running those steps again. It keeps to the run's recorded chain deadline
+// unless its context sets a new chain timeout.
+// MemoryCheckpointStore and FileCheckpointStore are built in.
+//
+// RunChainStream runs a chain and streams step_started, step_completed and
+// step_failed events, the chunk and progress events of steps that stream,
+// and a final done or error event carrying the RunResult. Every event is
+// tagged with its StepIndex and ToolID.
+//
+// Chains can also be stored as data: ParseChainDocument,
+// ParseChainDocumentYAML, and LoadChainDocument read a versioned JSON or YAML
+// chain document, and RunChainDocument runs one within its timeout.
+// CheckChain resolves every tool a chain uses and checks static args and
+// UsePrevious hand-offs against the tools' schemas, reporting all problems
+// before anything runs.
+// PlanChain returns the same findings as a serializable ChainPlan, along with
+// the backend each step would use and which args are static or dynamic.
+//
+// A chain can be published as a tool with NewCompositeTool: the tool's args
+// become the chain inputs, its result is the chain's final Structured value,
+// and its Handler plugs into a LocalRegistry. RunResult.Steps records the
+// nested chain's step results.
+//
+// # Graphs
+//
+// RunGraph executes steps with IDs and DependsOn edges. Independent steps run
+// concurrently up to a parallelism bound; steps with UsePrevious receive a map
+// of dependency ID to structured result at args["previous"].
 //
 // # Example
 //
@@ -0,0 +1,125 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// checkErrorPolicy validates a step or chain error policy. Nil is valid.
+func checkErrorPolicy(p *ErrorPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.OnTimeout != nil {
+		if p.OnTimeout.OnTimeout != nil {
+			return fmt.Errorf("%w: onTimeout policies cannot be nested", ErrInvalidChain)
+		}
+		if err := checkErrorPolicy(p.OnTimeout); err != nil {
+			return err
+		}
+	}
+	switch p.Action {
+	case "", ErrorActionAbort, ErrorActionContinue:
+		return nil
+	case ErrorActionFallback:
+		if p.FallbackToolID == "" {
+			return fmt.Errorf("%w: fallback policy requires fallbackToolId", ErrInvalidChain)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown error action %q", ErrInvalidChain, p.Action)
+	}
+}
+
+// errorPolicy returns the effective error policy for a step.
+func (r *DefaultRunner) errorPolicy(step ChainStep) ErrorPolicy {
+	if step.OnError != nil {
+		return *step.OnError
+	}
+	return r.cfg.ChainErrorPolicy
+}
+
+// runChainStep runs a step unless its When condition is false, and applies
+// its error policy on failure. The step runs with a context bounded by its
+// Timeout. It returns a non-nil error only when the chain must abort.
+// Cancellation, chain timeouts, and condition errors always abort.
+func (r *DefaultRunner) runChainStep(ctx context.Context, step ChainStep, scope *chainScope) (StepResult, error) {
+	if step.When != "" {
+		run, err := evalWhen(step.When, scope)
+		if err != nil {
+			sr := StepResult{
+				ID:     step.ID,
+				ToolID: step.ToolID,
+				Err:    WrapError(step.ToolID, nil, "when", err),
+				Policy: ErrorActionAbort,
+			}
+			return sr, sr.Err
+		}
+		if !run {
+			return StepResult{ID: step.ID, ToolID: step.ToolID, Skipped: true}, nil
+		}
+	}
+
+	stepCtx, cancel := stepContext(ctx, step)
+	sr, args := r.runStep(stepCtx, step, scope)
+	cancel()
+	if sr.Err == nil {
+		return sr, nil
+	}
+	cause := timeoutCause(stepCtx)
+	if cause != nil {
+		sr.Err = timeoutError(step, sr.Backend, cause)
+	}
+
+	// Cancellation and chain timeouts abort; step timeouts may have their
+	// own policy.
+	policy := r.errorPolicy(step)
+	if ctx.Err() != nil {
+		sr.Policy = ErrorActionAbort
+		return sr, sr.Err
+	}
+	if cause != nil && policy.OnTimeout != nil {
+		policy = *policy.OnTimeout
+	}
+
+	switch policy.Action {
+	case ErrorActionContinue:
+		sr.Policy = ErrorActionContinue
+		return sr, nil
+
+	case ErrorActionFallback:
+		sr.Policy = ErrorActionFallback
+		fb := r.runFallback(ctx, step, policy.FallbackToolID, args, sr.Err)
+		sr.Fallback = &fb
+		if fb.Err != nil {
+			return sr, errors.Join(sr.Err, fb.Err)
+		}
+		sr.Result = fb.Result
+		return sr, nil
+
+	default:
+		sr.Policy = ErrorActionAbort
+		return sr, sr.Err
+	}
+}
+
+// runFallback executes a fallback tool with the failed step's args and error.
+// The caller's args are copied, never modified.
+func (r *DefaultRunner) runFallback(ctx context.Context, step ChainStep, toolID string, failedArgs map[string]any, stepErr error) StepResult {
+	args := make(map[string]any, len(failedArgs)+1)
+	for k, v := range failedArgs {
+		args[k] = v
+	}
+	args["error"] = stepErr.Error()
+
+	result, err := r.execute(ctx, toolID, args)
+	return StepResult{
+		ID:            step.ID,
+		ToolID:        toolID,
+		Backend:       stepBackend(result, err),
+		BackendsTried: stepTried(result, err),
+		Result:        result,
+		Err:           err,
+	}
+}
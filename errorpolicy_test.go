@@ -0,0 +1,235 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func failingHandler(_ context.Context, _ map[string]any) (any, error) {
+	return nil, errTest
+}
+
+func TestRunChain_ErrorPolicy_AbortIsDefault(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": failingHandler,
+		"ok": func(_ context.Context, _ map[string]any) (any, error) {
+			return "ok", nil
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "fail"},
+		{ToolID: "ok"},
+	})
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunChain() error = %v, want ErrExecution", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	if results[0].Policy != ErrorActionAbort {
+		t.Errorf("results[0].Policy = %q, want abort", results[0].Policy)
+	}
+}
+
+func TestRunChain_ErrorPolicy_Continue(t *testing.T) {
+	var received map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": failingHandler,
+		"next": func(_ context.Context, args map[string]any) (any, error) {
+			received = args
+			return "done", nil
+		},
+	})
+
+	final, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "fail", OnError: &ErrorPolicy{Action: ErrorActionContinue}},
+		{ToolID: "next", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if !errors.Is(results[0].Err, ErrExecution) {
+		t.Errorf("results[0].Err = %v, want ErrExecution", results[0].Err)
+	}
+	if results[0].Policy != ErrorActionContinue {
+		t.Errorf("results[0].Policy = %q, want continue", results[0].Policy)
+	}
+	if v, ok := received["previous"]; !ok || v != nil {
+		t.Errorf("previous = %v (present %v), want nil", v, ok)
+	}
+	if final.Structured != "done" {
+		t.Errorf("final.Structured = %v, want done", final.Structured)
+	}
+	if results[1].Policy != "" {
+		t.Errorf("results[1].Policy = %q, want empty for successful step", results[1].Policy)
+	}
+}
+
+func TestRunChain_ErrorPolicy_Fallback(t *testing.T) {
+	var fallbackArgs, nextArgs map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": failingHandler,
+		"backup": func(_ context.Context, args map[string]any) (any, error) {
+			fallbackArgs = args
+			return "from-backup", nil
+		},
+		"next": func(_ context.Context, args map[string]any) (any, error) {
+			nextArgs = args
+			return "done", nil
+		},
+	})
+
+	stepArgs := map[string]any{"q": "x"}
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{
+			ID:      "primary",
+			ToolID:  "fail",
+			Args:    stepArgs,
+			OnError: &ErrorPolicy{Action: ErrorActionFallback, FallbackToolID: "backup"},
+		},
+		{ToolID: "next", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+
+	if fallbackArgs["q"] != "x" {
+		t.Errorf("fallback args[q] = %v, want x", fallbackArgs["q"])
+	}
+	if msg, _ := fallbackArgs["error"].(string); msg == "" {
+		t.Error("fallback should receive the step error at args[error]")
+	}
+	if _, ok := stepArgs["error"]; ok {
+		t.Error("fallback must not modify the step's args")
+	}
+
+	sr := results[0]
+	if sr.Policy != ErrorActionFallback {
+		t.Errorf("Policy = %q, want fallback", sr.Policy)
+	}
+	if !errors.Is(sr.Err, ErrExecution) || !strings.Contains(sr.Err.Error(), "fail") {
+		t.Errorf("Err = %v, want original step error", sr.Err)
+	}
+	if sr.Fallback == nil || sr.Fallback.ToolID != "backup" || sr.Fallback.Err != nil {
+		t.Fatalf("Fallback = %+v, want successful backup run", sr.Fallback)
+	}
+	if sr.Result.Structured != "from-backup" {
+		t.Errorf("Result.Structured = %v, want from-backup", sr.Result.Structured)
+	}
+	if nextArgs["previous"] != "from-backup" {
+		t.Errorf("next previous = %v, want from-backup", nextArgs["previous"])
+	}
+}
+
+func TestRunChain_ErrorPolicy_FallbackFailureAborts(t *testing.T) {
+	fallbackErr := errors.New("backup failed")
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": failingHandler,
+		"backup": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, fallbackErr
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "fail", OnError: &ErrorPolicy{Action: ErrorActionFallback, FallbackToolID: "backup"}},
+		{ToolID: "fail"},
+	})
+	if !strings.Contains(err.Error(), errTest.Error()) || !strings.Contains(err.Error(), fallbackErr.Error()) {
+		t.Fatalf("RunChain() error = %v, want both step and fallback errors", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	if results[0].Fallback == nil || !errors.Is(results[0].Fallback.Err, ErrExecution) {
+		t.Errorf("Fallback = %+v, want failed fallback", results[0].Fallback)
+	}
+}
+
+func TestRunChain_ErrorPolicy_ChainDefault(t *testing.T) {
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	for _, name := range []string{"fail", "ok"} {
+		mustRegisterTool(t, idx, testTool(name), testLocalBackend(name))
+	}
+	localReg.Register("fail", failingHandler)
+	localReg.Register("ok", func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil })
+
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+		WithChainErrorPolicy(ErrorPolicy{Action: ErrorActionContinue}),
+	)
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "fail"},
+		{ToolID: "fail", OnError: &ErrorPolicy{Action: ErrorActionAbort}},
+		{ToolID: "ok"},
+	})
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunChain() error = %v, want ErrExecution from step override", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if results[0].Policy != ErrorActionContinue || results[1].Policy != ErrorActionAbort {
+		t.Errorf("policies = [%q %q], want [continue abort]", results[0].Policy, results[1].Policy)
+	}
+}
+
+func TestRunChain_ErrorPolicy_Invalid(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"fail": failingHandler})
+
+	tests := []struct {
+		name   string
+		policy ErrorPolicy
+	}{
+		{name: "unknown action", policy: ErrorPolicy{Action: "retry"}},
+		{name: "fallback without tool", policy: ErrorPolicy{Action: ErrorActionFallback}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			_, results, err := runner.RunChain(context.Background(), []ChainStep{
+				{ToolID: "fail", OnError: &policy},
+			})
+			if !errors.Is(err, ErrInvalidChain) {
+				t.Errorf("RunChain() error = %v, want ErrInvalidChain", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("len(results) = %d, want 0", len(results))
+			}
+		})
+	}
+}
+
+func TestRunGraph_ErrorPolicy_ContinueRunsDependents(t *testing.T) {
+	var called bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": failingHandler,
+		"after": func(_ context.Context, _ map[string]any) (any, error) {
+			called = true
+			return "ok", nil
+		},
+	})
+
+	results, err := runner.RunGraph(context.Background(), []GraphStep{
+		{ChainStep: ChainStep{ID: "a", ToolID: "fail", OnError: &ErrorPolicy{Action: ErrorActionContinue}}},
+		{ChainStep: ChainStep{ID: "b", ToolID: "after"}, DependsOn: []string{"a"}},
+	}, 0)
+	if err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if !called {
+		t.Error("dependent should run when the failure was handled by continue")
+	}
+	if results[0].Policy != ErrorActionContinue {
+		t.Errorf("results[0].Policy = %q, want continue", results[0].Policy)
+	}
+}
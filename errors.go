@@ -31,6 +31,48 @@ var (
 	// ErrStreamNotSupported is returned when streaming is not supported
 	// by the executor or backend.
 	ErrStreamNotSupported = errors.New("streaming not supported")
+
+	// ErrInvalidChain is returned when a chain or graph definition is malformed,
+	// for example when step IDs are duplicated or dependencies form a cycle.
+	ErrInvalidChain = errors.New("invalid chain")
+
+	// ErrInvalidReference is returned when a step argument references an
+	// earlier step result through a path that is malformed or does not resolve.
+	ErrInvalidReference = errors.New("invalid reference")
+
+	// ErrDependencyFailed is recorded for graph steps that did not run because
+	// a step they depend on failed or the graph was aborted.
+	ErrDependencyFailed = errors.New("dependency failed")
+
+	// ErrCheckpointNotFound is returned when resuming a chain run that has
+	// no checkpoint.
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+	// ErrCircuitOpen is returned when a call fails fast because the circuit
+	// breaker of every candidate backend is open (see CircuitBreakers).
+	ErrCircuitOpen = errors.New("circuit open")
+
+	// ErrLimitExceeded is returned when a call is rejected by a concurrency
+	// or rate limit (see Limiter). Errors matching it carry a *LimitError.
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrStepTimeout is returned when a chain step's Timeout expires.
+	// Errors matching it also match context.DeadlineExceeded.
+	ErrStepTimeout = errors.New("step timeout")
+
+	// ErrChainTimeout is returned when a chain's overall timeout expires
+	// (see WithChainTimeout). Errors matching it also match
+	// context.DeadlineExceeded.
+	ErrChainTimeout = errors.New("chain timeout")
+
+	// ErrCallTimeout is returned when the runner's own execution timeout for
+	// a backend call expires (see TimeoutConfig), as opposed to the caller's
+	// deadline. Errors matching it also match context.DeadlineExceeded.
+	ErrCallTimeout = errors.New("call timeout")
+
+	// ErrSecretNotFound is returned when a SecretProvider has no secret for a
+	// secret reference in the args.
+	ErrSecretNotFound = errors.New("secret not found")
 )
 
 // ToolError wraps an error with tool execution context.
@@ -45,6 +87,19 @@ type ToolError struct {
 	// Op is the operation that failed (e.g., "resolve", "validate_input", "execute").
 	Op string
 
+	// BackendsTried lists every backend the call was dispatched to before it
+	// failed, in order. Nil for failures before dispatch.
+	BackendsTried []toolmodel.ToolBackend
+
+	// Attempts is the number of attempts Run made before it failed,
+	// including the first. Zero for failures before the first attempt, such
+	// as validation errors.
+	Attempts int
+
+	// Steps holds the step results of a failed CompositeTool's chain, up to
+	// and including the step that failed. Nil for other tools.
+	Steps []StepResult
+
 	// Err is the underlying error.
 	Err error
 }
@@ -0,0 +1,473 @@
+package toolrun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// condition is a compiled When expression.
+//
+// Grammar:
+//
+//	expr    = or
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = "(" expr ")" | string | number | "true" | "false" | "null" | path
+//
+// Paths use the same syntax and roots as argument references
+// ("steps", "previous", "inputs"). A path with no value evaluates to null.
+type condition struct {
+	src  string
+	root exprNode
+}
+
+// exprNode is a node in a compiled condition.
+type exprNode interface {
+	eval(s *chainScope) (any, error)
+}
+
+type literalNode struct{ v any }
+
+type pathNode struct {
+	src  string
+	segs []pathSegment
+}
+
+type notNode struct{ x exprNode }
+
+type binaryNode struct {
+	op   string
+	l, r exprNode
+}
+
+// compileCondition parses a When expression.
+func compileCondition(src string) (*condition, error) {
+	toks, err := tokenizeCondition(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return &condition{src: src, root: root}, nil
+}
+
+// evalWhen compiles and evaluates a step's When condition.
+func evalWhen(src string, scope *chainScope) (bool, error) {
+	cond, err := compileCondition(src)
+	if err != nil {
+		return false, fmt.Errorf("%w: when %q: %v", ErrInvalidChain, src, err)
+	}
+	run, err := cond.eval(scope)
+	if err != nil {
+		return false, fmt.Errorf("%w: when %q: %v", ErrInvalidChain, src, err)
+	}
+	return run, nil
+}
+
+// checkWhen statically validates a When condition: it must parse and its
+// paths must only reference earlier steps.
+func checkWhen(src string, earlier map[string]bool) error {
+	if src == "" {
+		return nil
+	}
+	cond, err := compileCondition(src)
+	if err != nil {
+		return fmt.Errorf("%w: when %q: %v", ErrInvalidChain, src, err)
+	}
+	for _, segs := range cond.paths() {
+		if err := checkPath(segs, earlier, false); err != nil {
+			return fmt.Errorf("%w: when %q: %v", ErrInvalidChain, src, err)
+		}
+	}
+	return nil
+}
+
+// eval evaluates the condition against scope and reports its truthiness.
+func (c *condition) eval(s *chainScope) (bool, error) {
+	v, err := c.root.eval(s)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// paths returns every path referenced by the condition.
+func (c *condition) paths() [][]pathSegment {
+	var out [][]pathSegment
+	var walk func(n exprNode)
+	walk = func(n exprNode) {
+		switch v := n.(type) {
+		case *pathNode:
+			out = append(out, v.segs)
+		case *notNode:
+			walk(v.x)
+		case *binaryNode:
+			walk(v.l)
+			walk(v.r)
+		}
+	}
+	walk(c.root)
+	return out
+}
+
+func (n *literalNode) eval(_ *chainScope) (any, error) { return n.v, nil }
+
+func (n *pathNode) eval(s *chainScope) (any, error) {
+	v, err := s.lookup(n.segs)
+	if errors.Is(err, errPathMissing) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", n.src, err)
+	}
+	return v, nil
+}
+
+func (n *notNode) eval(s *chainScope) (any, error) {
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+func (n *binaryNode) eval(s *chainScope) (any, error) {
+	l, err := n.l.eval(s)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit logical operators.
+	switch n.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+	}
+	r, err := n.r.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return truthy(r), nil
+	case "==":
+		return valuesEqual(l, r), nil
+	case "!=":
+		return !valuesEqual(l, r), nil
+	}
+
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			return compareOrdered(n.op, lf, rf), nil
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			return compareOrdered(n.op, ls, rs), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %T %s %T", l, n.op, r)
+}
+
+func compareOrdered[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+// valuesEqual compares numbers numerically and other values structurally.
+func valuesEqual(a, b any) bool {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(jsonNormalize(a), jsonNormalize(b))
+}
+
+// toFloat converts Go and JSON numeric values to float64.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// truthy reports whether v counts as true: null, false, zero, empty strings
+// and empty collections are false; everything else is true.
+func truthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	switch val := jsonNormalize(v).(type) {
+	case string:
+		return val != ""
+	case map[string]any:
+		return len(val) > 0
+	case []any:
+		return len(val) > 0
+	}
+	return true
+}
+
+// condToken is a lexical token in a condition.
+type condToken struct {
+	kind string // "op", "(", ")", "lit", "path"
+	text string
+	val  any
+}
+
+// tokenizeCondition splits a condition into tokens.
+func tokenizeCondition(src string) ([]condToken, error) {
+	var toks []condToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, condToken{kind: string(c), text: string(c)})
+			i++
+		case strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "<="), strings.HasPrefix(src[i:], ">="),
+			strings.HasPrefix(src[i:], "&&"), strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, condToken{kind: "op", text: src[i : i+2]})
+			i += 2
+		case c == '<' || c == '>' || c == '!':
+			toks = append(toks, condToken{kind: "op", text: string(c)})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			raw := src[i : j+1]
+			if c == '\'' {
+				raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+			}
+			str, err := strconv.Unquote(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", src[i:j+1])
+			}
+			toks = append(toks, condToken{kind: "lit", text: src[i : j+1], val: str})
+			i = j + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(src) && strings.IndexByte("0123456789.eE+-", src[j]) >= 0 {
+				j++
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:j])
+			}
+			toks = append(toks, condToken{kind: "lit", text: src[i:j], val: f})
+			i = j
+		default:
+			j := i
+			for j < len(src) && strings.IndexByte(" \t\r\n()=!<>&|", src[j]) < 0 {
+				if src[j] == '[' {
+					end := strings.IndexByte(src[j:], ']')
+					if end < 0 {
+						return nil, fmt.Errorf("unterminated '[' at offset %d", j)
+					}
+					j += end
+				}
+				j++
+			}
+			if j == i {
+				// A lone '=', '&', or '|' is not an operator.
+				return nil, fmt.Errorf("unexpected %q at offset %d", src[i], i)
+			}
+			word := src[i:j]
+			switch word {
+			case "true":
+				toks = append(toks, condToken{kind: "lit", text: word, val: true})
+			case "false":
+				toks = append(toks, condToken{kind: "lit", text: word, val: false})
+			case "null":
+				toks = append(toks, condToken{kind: "lit", text: word, val: nil})
+			default:
+				toks = append(toks, condToken{kind: "path", text: word})
+			}
+			i = j
+		}
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return toks, nil
+}
+
+// condParser is a recursive-descent parser over condition tokens.
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.toks[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *condParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", l: left, r: right}
+	}
+}
+
+func (p *condParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", l: left, r: right}
+	}
+}
+
+func (p *condParser) parseUnary() (exprNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: op, l: left, r: right}, nil
+}
+
+func (p *condParser) parseOperand() (exprNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch tok.kind {
+	case "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.toks) || p.toks[p.pos].kind != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return x, nil
+	case "lit":
+		return &literalNode{v: tok.val}, nil
+	case "path":
+		segs, err := parsePath(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", tok.text, err)
+		}
+		return &pathNode{src: tok.text, segs: segs}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+}
@@ -0,0 +1,185 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCondition_Eval(t *testing.T) {
+	scope := &chainScope{
+		previous: map[string]any{"status": "needs_review", "count": 3, "tags": []any{"a"}},
+		inputs:   map[string]any{"env": "prod", "dry": false},
+		steps: []*StepResult{
+			{ID: "fetch", Result: RunResult{Structured: map[string]any{"ok": true}}},
+		},
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`previous.status == "needs_review"`, true},
+		{`previous.status != 'needs_review'`, false},
+		{`previous.count > 2 && previous.count <= 3`, true},
+		{`previous.count == 3.0`, true},
+		{`previous.missing == null`, true},
+		{`previous.missing`, false},
+		{`!inputs.dry`, true},
+		{`inputs.env == "dev" || steps.fetch.structured.ok`, true},
+		{`(inputs.env == "dev" || inputs.env == "prod") && previous.tags`, true},
+		{`steps[0].structured.ok == false`, false},
+		{`"b" > "a"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			cond, err := compileCondition(tt.src)
+			if err != nil {
+				t.Fatalf("compileCondition() error = %v", err)
+			}
+			got, err := cond.eval(scope)
+			if err != nil {
+				t.Fatalf("eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_CompileErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`previous.status ==`,
+		`(previous.ok`,
+		`"unterminated`,
+		`previous..x`,
+		`a == b c`,
+		`previous.status = "ok"`,
+		`previous.ok & inputs.ok`,
+		`previous.ok | inputs.ok`,
+		`=`,
+	} {
+		if _, err := compileCondition(src); err == nil {
+			t.Errorf("compileCondition(%q) should fail", src)
+		}
+	}
+}
+
+func TestCheckWhen_LoneOperators(t *testing.T) {
+	for _, src := range []string{`previous.status = "ok"`, `previous.ok & true`, `previous.ok | true`} {
+		err := checkWhen(src, nil)
+		if !errors.Is(err, ErrInvalidChain) || !strings.Contains(err.Error(), "unexpected") {
+			t.Errorf("checkWhen(%q) error = %v, want an unexpected operator", src, err)
+		}
+	}
+}
+
+func TestCondition_EvalErrors(t *testing.T) {
+	scope := &chainScope{previous: map[string]any{"n": 1}}
+	for _, src := range []string{
+		`previous.n < "x"`,
+		`steps.nope.structured`,
+	} {
+		cond, err := compileCondition(src)
+		if err != nil {
+			t.Fatalf("compileCondition(%q) error = %v", src, err)
+		}
+		if _, err := cond.eval(scope); err == nil {
+			t.Errorf("eval(%q) should fail", src)
+		}
+	}
+}
+
+func TestRunChain_When(t *testing.T) {
+	var calls []string
+	var finalArgs map[string]any
+	record := func(name string, out any) LocalHandler {
+		return func(_ context.Context, args map[string]any) (any, error) {
+			calls = append(calls, name)
+			if name == "notify" {
+				finalArgs = args
+			}
+			return out, nil
+		}
+	}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"classify": record("classify", map[string]any{"status": "ok"}),
+		"review":   record("review", "reviewed"),
+		"notify":   record("notify", "notified"),
+	})
+
+	ctx := WithChainInputs(context.Background(), map[string]any{"notify": true})
+	final, results, err := runner.RunChain(ctx, []ChainStep{
+		{ID: "classify", ToolID: "classify"},
+		{ToolID: "review", When: `previous.status == "needs_review"`},
+		{ToolID: "notify", When: `inputs.notify`, UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+
+	if len(calls) != 2 || calls[0] != "classify" || calls[1] != "notify" {
+		t.Errorf("calls = %v, want [classify notify]", calls)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+	if !results[1].Skipped || results[1].Err != nil {
+		t.Errorf("results[1] = %+v, want skipped without error", results[1])
+	}
+	if results[2].Skipped {
+		t.Error("results[2] should not be skipped")
+	}
+	// Skipped steps leave previous unchanged.
+	prev, _ := finalArgs["previous"].(map[string]any)
+	if prev["status"] != "ok" {
+		t.Errorf("previous = %v, want classify output", finalArgs["previous"])
+	}
+	if final.Structured != "notified" {
+		t.Errorf("final.Structured = %v, want notified", final.Structured)
+	}
+}
+
+func TestRunChain_When_LastStepSkipped(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"a": func(_ context.Context, _ map[string]any) (any, error) { return "a-out", nil },
+		"b": func(_ context.Context, _ map[string]any) (any, error) { return "b-out", nil },
+	})
+
+	final, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "a"},
+		{ToolID: "b", When: `false`},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if !results[1].Skipped {
+		t.Error("results[1] should be skipped")
+	}
+	if final.Structured != "a-out" {
+		t.Errorf("final.Structured = %v, want result of last executed step", final.Structured)
+	}
+}
+
+func TestRunChain_When_Invalid(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"a": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+
+	for _, when := range []string{`previous.status ==`, `steps.later.structured.ok`, `previous.status = "ok"`, `previous.ok & true`, `previous.ok | true`} {
+		_, results, err := runner.RunChain(context.Background(), []ChainStep{
+			{ToolID: "a", When: when},
+			{ID: "later", ToolID: "a"},
+		})
+		if !errors.Is(err, ErrInvalidChain) {
+			t.Errorf("RunChain(when=%q) error = %v, want ErrInvalidChain", when, err)
+		}
+		if len(results) != 0 {
+			t.Errorf("RunChain(when=%q) ran %d steps, want 0", when, len(results))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// FailoverPolicy controls failover from a tool's selected backend to its
+// alternates. The zero value disables failover.
+//
+// Backends are tried in preference order: the BackendSelector's choice
+// first, then its choice among the remaining backends, and so on.
+type FailoverPolicy struct {
+	// Enabled turns on failover.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ShouldFailover reports whether a failed backend should be abandoned
+	// for the next one. Nil uses DefaultRetryable, so execution failures fail
+	// over while validation errors do not.
+	ShouldFailover func(err error) bool `json:"-"`
+}
+
+// shouldFailover reports whether err may fail over under the policy.
+func (p FailoverPolicy) shouldFailover(err error) bool {
+	if p.ShouldFailover != nil {
+		return p.ShouldFailover(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// orderBackends returns a tool's backends in preference order by applying
+// the BackendSelector repeatedly to the backends not yet chosen. Backends
+// whose circuit is open are only chosen when no other backend is available.
+// Without failover or hedging only the selected backend is returned.
+func (r *DefaultRunner) orderBackends(tool toolmodel.Tool, backends []toolmodel.ToolBackend) ([]toolmodel.ToolBackend, error) {
+	if len(backends) == 0 {
+		return nil, ErrNoBackends
+	}
+	all := r.cfg.Failover.Enabled || r.hedges(tool)
+	var ready, open []toolmodel.ToolBackend
+	for _, b := range backends {
+		if r.cfg.CircuitBreakers.ready(b) {
+			ready = append(ready, b)
+		} else {
+			open = append(open, b)
+		}
+	}
+
+	var ordered []toolmodel.ToolBackend
+	for _, group := range [][]toolmodel.ToolBackend{ready, open} {
+		ordered = append(ordered, r.selectionOrder(group, all)...)
+		if len(ordered) > 0 && !all {
+			return ordered[:1], nil
+		}
+	}
+	return ordered, nil
+}
+
+// selectionOrder orders backends by applying the BackendSelector repeatedly,
+// or returns only its first choice unless all is set.
+func (r *DefaultRunner) selectionOrder(backends []toolmodel.ToolBackend, all bool) []toolmodel.ToolBackend {
+	ordered := make([]toolmodel.ToolBackend, 0, len(backends))
+	rest := append([]toolmodel.ToolBackend(nil), backends...)
+	for len(rest) > 0 {
+		i := indexBackend(rest, r.cfg.BackendSelector(rest))
+		if i < 0 {
+			// The selector returned a backend it was not offered; keep the
+			// remaining order.
+			return append(ordered, rest...)
+		}
+		ordered = append(ordered, rest[i])
+		rest = append(rest[:i], rest[i+1:]...)
+		if !all {
+			break
+		}
+	}
+	return ordered
+}
+
+// backendKey returns a string identifying a backend binding. Unlike
+// instanceKey, provider backends are distinguished by tool as well.
+func backendKey(b toolmodel.ToolBackend) string {
+	switch {
+	case b.Kind == toolmodel.BackendKindMCP && b.MCP != nil:
+		return "mcp:" + b.MCP.ServerName
+	case b.Kind == toolmodel.BackendKindProvider && b.Provider != nil:
+		return "provider:" + b.Provider.ProviderID + ":" + b.Provider.ToolID
+	case b.Kind == toolmodel.BackendKindLocal && b.Local != nil:
+		return "local:" + b.Local.Name
+	}
+	return string(b.Kind)
+}
+
+// indexBackend returns the index of b in backends, or -1.
+func indexBackend(backends []toolmodel.ToolBackend, b toolmodel.ToolBackend) int {
+	key := backendKey(b)
+	for i, candidate := range backends {
+		if backendKey(candidate) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// runBackends makes one attempt at a call: it dispatches to each backend in
+// order until one succeeds or the failover policy stops, recording every
+// backend it tries in tried. Backends whose circuit is open are skipped.
+// Hedged tools are run with runHedged instead.
+func (r *DefaultRunner) runBackends(ctx context.Context, toolID string, tool toolmodel.Tool, backends []toolmodel.ToolBackend, args map[string]any, tried *[]toolmodel.ToolBackend, opts dispatchOptions) (RunResult, error) {
+	if len(backends) > 1 && r.hedges(tool) {
+		return r.runHedged(ctx, toolID, tool, backends, args, tried, opts)
+	}
+	var err error
+	for _, backend := range backends {
+		if err != nil && (ctx.Err() != nil || !errors.Is(err, ErrCircuitOpen) && !r.cfg.Failover.shouldFailover(err)) {
+			break
+		}
+		if !r.cfg.CircuitBreakers.allow(backend) {
+			// Keep the error of a backend that was dispatched to, if any.
+			if err == nil {
+				err = WrapError(toolID, &backend, "circuit_open", ErrCircuitOpen)
+			}
+			continue
+		}
+		if indexBackend(*tried, backend) < 0 {
+			*tried = append(*tried, backend)
+		}
+		var result RunResult
+		result, err = r.runBackend(ctx, toolID, tool, backend, args, opts)
+		r.cfg.CircuitBreakers.record(ctx, backend, err)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return RunResult{}, err
+}
+
+// runBackend dispatches a call to a single backend, within the limits and
+// execution timeout that apply to it, and normalizes and validates its
+// result. The outcome is reported to the BackendObserver unless ctx ended
+// the call. With a sink in opts, backends that stream are dispatched with
+// streaming.
+func (r *DefaultRunner) runBackend(ctx context.Context, toolID string, tool toolmodel.Tool, backend toolmodel.ToolBackend, args map[string]any, opts dispatchOptions) (RunResult, error) {
+	release, err := r.cfg.Limiter.acquire(ctx, tool, backend)
+	if err != nil {
+		return RunResult{}, WrapError(toolID, &backend, "limit", err)
+	}
+	defer release()
+
+	// 4. Dispatch
+	call := Call{ToolID: toolID, Args: args, Tool: tool, Backend: backend}
+	if call.Args, err = r.beforeDispatch(ctx, call); err != nil {
+		return RunResult{}, err
+	}
+	sendArgs, red, err := r.resolveSecrets(ctx, call, opts.secrets)
+	if err != nil {
+		return RunResult{}, err
+	}
+	callCtx, cancel, timeout := r.callContext(ctx, tool, backend)
+	defer cancel()
+	start := time.Now()
+	var dispatchResult *dispatchResult
+	if opts.sink != nil {
+		dispatchResult, err = r.dispatchStreamed(callCtx, toolID, tool, backend, sendArgs, red, opts.sink)
+	} else {
+		dispatchResult, err = r.dispatch(callCtx, tool, backend, sendArgs)
+	}
+	latency := time.Since(start)
+	var result RunResult
+	switch {
+	case err != nil && callTimedOut(callCtx):
+		err = attachNestedSteps(callTimeoutError(toolID, backend, timeout), dispatchResult, red)
+	case err != nil:
+		err = WrapError(toolID, &backend, "execute", fmt.Errorf("%w: %s", ErrExecution, red.string(err.Error())))
+		err = attachNestedSteps(err, dispatchResult, red)
+	default:
+		// 5-6. Normalize and validate output
+		red.dispatchResult(dispatchResult)
+		result, err = r.finish(ctx, call, dispatchResult)
+	}
+	if r.cfg.BackendObserver != nil && ctx.Err() == nil {
+		r.cfg.BackendObserver.ObserveBackend(backend, latency, err)
+	}
+	return result, err
+}
+
+// attachNestedSteps attaches the step results of a failed composite tool's
+// chain, if any, to its ToolError.
+func attachNestedSteps(err error, dr *dispatchResult, red *redactor) error {
+	var toolErr *ToolError
+	if dr != nil && len(dr.steps) > 0 && errors.As(err, &toolErr) {
+		toolErr.Steps = red.steps(dr.steps)
+	}
+	return err
+}
+
+// recordTried attaches the backends tried by a call to its result or error.
+func recordTried(result *RunResult, err error, tried []toolmodel.ToolBackend) {
+	if err == nil {
+		result.BackendsTried = tried
+		return
+	}
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		toolErr.BackendsTried = tried
+	}
+}
+
+// stepTried returns the backends tried by a step's call.
+func stepTried(result RunResult, err error) []toolmodel.ToolBackend {
+	if err == nil {
+		return result.BackendsTried
+	}
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr.BackendsTried
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// newFailoverTestRunner registers "multi" with local, provider, and MCP
+// backends. The local handler always fails.
+func newFailoverTestRunner(t *testing.T, opts ...ConfigOption) (*DefaultRunner, *mockProviderExecutor, *mockMCPExecutor) {
+	t.Helper()
+	idx := newMockIndex()
+	tool := testTool("multi")
+	mustRegisterTool(t, idx, tool, testMCPBackend("srv"))
+	mustRegisterTool(t, idx, tool, testProviderBackend("p", "multi"))
+	mustRegisterTool(t, idx, tool, testLocalBackend("multi"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("multi", func(_ context.Context, _ map[string]any) (any, error) {
+		return nil, errTest
+	})
+	provider := newMockProviderExecutor()
+	mcpExec := newMockMCPExecutor()
+	runner := NewRunner(append([]ConfigOption{
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithProviderExecutor(provider),
+		WithMCPExecutor(mcpExec),
+		WithValidation(false, false),
+	}, opts...)...)
+	return runner, provider, mcpExec
+}
+
+func backendKinds(backends []toolmodel.ToolBackend) []toolmodel.BackendKind {
+	kinds := make([]toolmodel.BackendKind, len(backends))
+	for i, b := range backends {
+		kinds[i] = b.Kind
+	}
+	return kinds
+}
+
+func TestRun_Failover(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t, WithFailover(FailoverPolicy{Enabled: true}))
+	provider.CallToolResult = "from provider"
+
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != "from provider" || result.Backend.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("result = %v from %s, want provider result", result.Structured, result.Backend.Kind)
+	}
+	got := backendKinds(result.BackendsTried)
+	if len(got) != 2 || got[0] != toolmodel.BackendKindLocal || got[1] != toolmodel.BackendKindProvider {
+		t.Errorf("BackendsTried = %v, want [local provider]", got)
+	}
+}
+
+func TestRun_FailoverExhausted(t *testing.T) {
+	runner, provider, mcpExec := newFailoverTestRunner(t, WithFailover(FailoverPolicy{Enabled: true}))
+	provider.CallToolErr = errTest
+	mcpExec.CallToolErr = errTest
+
+	_, err := runner.Run(context.Background(), "multi", nil)
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || !errors.Is(err, ErrExecution) {
+		t.Fatalf("Run() error = %v, want ToolError with ErrExecution", err)
+	}
+	if toolErr.Backend.Kind != toolmodel.BackendKindMCP {
+		t.Errorf("Backend = %s, want the last backend (mcp)", toolErr.Backend.Kind)
+	}
+	if got := backendKinds(toolErr.BackendsTried); len(got) != 3 {
+		t.Errorf("BackendsTried = %v, want all three backends", got)
+	}
+}
+
+func TestRun_FailoverDisabled(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t)
+	provider.CallToolResult = "from provider"
+
+	_, err := runner.Run(context.Background(), "multi", nil)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("Run() error = %v, want ErrExecution", err)
+	}
+	if provider.CallCount != 0 {
+		t.Errorf("provider calls = %d, want 0 without failover", provider.CallCount)
+	}
+}
+
+func TestRun_FailoverPredicate(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t, WithFailover(FailoverPolicy{
+		Enabled:        true,
+		ShouldFailover: func(err error) bool { return false },
+	}))
+	provider.CallToolResult = "from provider"
+
+	if _, err := runner.Run(context.Background(), "multi", nil); err == nil {
+		t.Fatal("Run() error = nil, want the local failure")
+	}
+	if provider.CallCount != 0 {
+		t.Errorf("provider calls = %d, want 0", provider.CallCount)
+	}
+}
+
+func TestRunChain_FailoverRecordsBackends(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t, WithFailover(FailoverPolicy{Enabled: true}))
+	provider.CallToolResult = "ok"
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{{ToolID: "multi"}})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if got := backendKinds(results[0].BackendsTried); len(got) != 2 {
+		t.Errorf("StepResult.BackendsTried = %v, want [local provider]", got)
+	}
+	if results[0].Backend.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("StepResult.Backend = %s, want provider", results[0].Backend.Kind)
+	}
+}
+
+func TestOrderBackends(t *testing.T) {
+	runner := NewRunner(WithFailover(FailoverPolicy{Enabled: true}))
+	backends := []toolmodel.ToolBackend{
+		testMCPBackend("srv"),
+		testLocalBackend("l"),
+		testProviderBackend("p", "t"),
+	}
+
+	ordered, err := runner.orderBackends(testTool("t"), backends)
+	if err != nil {
+		t.Fatalf("orderBackends() error = %v", err)
+	}
+	got := backendKinds(ordered)
+	want := []toolmodel.BackendKind{toolmodel.BackendKindLocal, toolmodel.BackendKindProvider, toolmodel.BackendKindMCP}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("orderBackends() = %v, want %v", got, want)
+		}
+	}
+	if len(backends) != 3 || backends[0].Kind != toolmodel.BackendKindMCP {
+		t.Error("orderBackends() modified its input")
+	}
+}
@@ -0,0 +1,146 @@
+package toolrun
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultItemArg is the arg name that receives each element of a ForEach step.
+const defaultItemArg = "item"
+
+// checkForEach statically validates a ForEach path.
+func checkForEach(step ChainStep, earlier map[string]bool) error {
+	if step.ForEach == "" {
+		if step.Concurrency != 0 || step.ItemArg != "" {
+			return fmt.Errorf("%w: concurrency and itemArg require forEach", ErrInvalidChain)
+		}
+		return nil
+	}
+	if step.Concurrency < 0 {
+		return fmt.Errorf("%w: negative concurrency %d", ErrInvalidChain, step.Concurrency)
+	}
+	segs, err := parsePath(step.ForEach)
+	if err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidReference, step.ForEach, err)
+	}
+	if err := checkPath(segs, earlier, false); err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidReference, step.ForEach, err)
+	}
+	return nil
+}
+
+// forEachItems resolves a ForEach path to the list it iterates over.
+func forEachItems(expr string, scope *chainScope) ([]any, error) {
+	segs, err := parsePath(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+	}
+	v, err := scope.lookup(segs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+	}
+	items, ok := jsonNormalize(v).([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q: want array, got %T", ErrInvalidReference, expr, v)
+	}
+	return items, nil
+}
+
+// runForEach runs a ForEach step: the tool is called once per element with
+// the element at args[ItemArg], at most Concurrency calls at a time.
+// After the first failed iteration no new iterations start. The step result
+// holds the outputs in element order, and Iterations holds one StepResult per
+// iteration that ran. The returned args are those of the first failed
+// iteration, for use by a fallback.
+func (r *DefaultRunner) runForEach(ctx context.Context, step ChainStep, scope *chainScope) (StepResult, map[string]any) {
+	sr := StepResult{ID: step.ID, ToolID: step.ToolID}
+
+	items, err := forEachItems(step.ForEach, scope)
+	if err != nil {
+		sr.Err = WrapError(step.ToolID, nil, "for_each", err)
+		return sr, nil
+	}
+
+	itemArg := step.ItemArg
+	if itemArg == "" {
+		itemArg = defaultItemArg
+	}
+	limit := step.Concurrency
+	if limit <= 0 {
+		limit = 1
+	}
+
+	iterations := make([]StepResult, len(items))
+	iterArgs := make([]map[string]any, len(items))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	launched := 0
+
+	for i, item := range items {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			<-sem
+			break
+		}
+		launched++
+		wg.Add(1)
+		go func(i int, item any) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			itemScope := *scope
+			itemScope.item = item
+			args, err := r.buildChainArgs(step, &itemScope)
+			if err != nil {
+				iterations[i] = StepResult{ID: step.ID, ToolID: step.ToolID, Err: WrapError(step.ToolID, nil, "resolve_args", err)}
+			} else {
+				args[itemArg] = item
+				iterArgs[i] = args
+				result, err := r.execute(ctx, step.ToolID, args)
+				iterations[i] = StepResult{
+					ID:            step.ID,
+					ToolID:        step.ToolID,
+					Backend:       stepBackend(result, err),
+					BackendsTried: stepTried(result, err),
+					Result:        result,
+					Err:           err,
+				}
+			}
+			if iterations[i].Err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	sr.Iterations = iterations[:launched]
+	var failedArgs map[string]any
+	outputs := make([]any, 0, launched)
+	for i, it := range sr.Iterations {
+		if it.Err != nil && sr.Err == nil {
+			sr.Err = it.Err
+			failedArgs = iterArgs[i]
+		}
+		outputs = append(outputs, it.Result.Structured)
+	}
+	if sr.Err == nil && launched < len(items) {
+		sr.Err = ctx.Err()
+	}
+	if len(sr.Iterations) > 0 {
+		sr.Backend = sr.Iterations[0].Backend
+		sr.Result.Tool = sr.Iterations[0].Result.Tool
+		sr.Result.Backend = sr.Iterations[0].Result.Backend
+	}
+	if sr.Err == nil {
+		sr.Result.Structured = outputs
+	}
+	return sr, failedArgs
+}
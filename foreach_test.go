@@ -0,0 +1,200 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunChain_ForEach(t *testing.T) {
+	var mu sync.Mutex
+	var seen []map[string]any
+	var nextArgs map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"list": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{"items": []any{
+				map[string]any{"id": "a"},
+				map[string]any{"id": "b"},
+				map[string]any{"id": "c"},
+			}}, nil
+		},
+		"get": func(_ context.Context, args map[string]any) (any, error) {
+			mu.Lock()
+			seen = append(seen, args)
+			mu.Unlock()
+			return "got-" + args["id"].(string), nil
+		},
+		"merge": func(_ context.Context, args map[string]any) (any, error) {
+			nextArgs = args
+			return "merged", nil
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ID: "list", ToolID: "list"},
+		{ToolID: "get", ForEach: "previous.items", ItemArg: "entry", Args: map[string]any{"id": "{{item.id}}"}},
+		{ToolID: "merge", UsePrevious: true},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("get called %d times, want 3", len(seen))
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if seen[i]["id"] != want {
+			t.Errorf("call %d id = %v, want %s", i, seen[i]["id"], want)
+		}
+		if entry, _ := seen[i]["entry"].(map[string]any); entry["id"] != want {
+			t.Errorf("call %d entry = %v, want element %s", i, seen[i]["entry"], want)
+		}
+	}
+
+	mapStep := results[1]
+	if len(mapStep.Iterations) != 3 {
+		t.Fatalf("len(Iterations) = %d, want 3", len(mapStep.Iterations))
+	}
+	outputs, ok := mapStep.Result.Structured.([]any)
+	if !ok || len(outputs) != 3 || outputs[0] != "got-a" || outputs[2] != "got-c" {
+		t.Errorf("Structured = %v, want [got-a got-b got-c]", mapStep.Result.Structured)
+	}
+	if prev, _ := nextArgs["previous"].([]any); len(prev) != 3 {
+		t.Errorf("next previous = %v, want collected outputs", nextArgs["previous"])
+	}
+}
+
+func TestRunChain_ForEach_ConcurrencyBound(t *testing.T) {
+	var inFlight, maxInFlight int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"list": func(_ context.Context, _ map[string]any) (any, error) {
+			return []int{1, 2, 3, 4, 5, 6}, nil
+		},
+		"work": func(_ context.Context, args map[string]any) (any, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			return args["item"], nil
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "list"},
+		{ToolID: "work", ForEach: "previous", Concurrency: 2},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 || got < 1 {
+		t.Errorf("max in-flight = %d, want 1..2", got)
+	}
+	outputs, _ := results[1].Result.Structured.([]any)
+	if len(outputs) != 6 || outputs[0] != float64(1) || outputs[5] != float64(6) {
+		t.Errorf("outputs = %v, want elements in order", outputs)
+	}
+}
+
+func TestRunChain_ForEach_FailureStopsIterations(t *testing.T) {
+	calls := 0
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"list": func(_ context.Context, _ map[string]any) (any, error) {
+			return []any{"ok", "bad", "never"}, nil
+		},
+		"work": func(_ context.Context, args map[string]any) (any, error) {
+			calls++
+			if args["item"] == "bad" {
+				return nil, errTest
+			}
+			return args["item"], nil
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "list"},
+		{ToolID: "work", ForEach: "previous", OnError: &ErrorPolicy{Action: ErrorActionContinue}},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2 (no iterations after failure)", calls)
+	}
+	mapStep := results[1]
+	if !errors.Is(mapStep.Err, ErrExecution) {
+		t.Errorf("Err = %v, want ErrExecution", mapStep.Err)
+	}
+	if mapStep.Policy != ErrorActionContinue {
+		t.Errorf("Policy = %q, want continue", mapStep.Policy)
+	}
+	if len(mapStep.Iterations) != 2 || mapStep.Iterations[0].Err != nil || mapStep.Iterations[1].Err == nil {
+		t.Errorf("Iterations = %+v, want [ok, failed]", mapStep.Iterations)
+	}
+}
+
+func TestRunChain_ForEach_EmptyAndInvalid(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"empty": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{"items": []any{}, "name": "x"}, nil
+		},
+		"work": func(_ context.Context, _ map[string]any) (any, error) {
+			t.Error("work should not be called")
+			return nil, nil
+		},
+	})
+
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "empty"},
+		{ToolID: "work", ForEach: "previous.items"},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if outputs, ok := results[1].Result.Structured.([]any); !ok || len(outputs) != 0 {
+		t.Errorf("Structured = %#v, want empty array", results[1].Result.Structured)
+	}
+
+	_, _, err = runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "empty"},
+		{ToolID: "work", ForEach: "previous.name"},
+	})
+	if !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("RunChain(non-array) error = %v, want ErrInvalidReference", err)
+	}
+}
+
+func TestRunChain_ForEach_StaticChecks(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"work": func(_ context.Context, _ map[string]any) (any, error) { return nil, nil },
+	})
+
+	tests := []struct {
+		name string
+		step ChainStep
+		want error
+	}{
+		{name: "item outside forEach", step: ChainStep{ToolID: "work", Args: map[string]any{"x": "{{item.id}}"}}, want: ErrInvalidReference},
+		{name: "bad path", step: ChainStep{ToolID: "work", ForEach: "previous..x"}, want: ErrInvalidReference},
+		{name: "item in forEach path", step: ChainStep{ToolID: "work", ForEach: "item.list"}, want: ErrInvalidReference},
+		{name: "concurrency without forEach", step: ChainStep{ToolID: "work", Concurrency: 2}, want: ErrInvalidChain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, results, err := runner.RunChain(context.Background(), []ChainStep{tt.step})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("RunChain() error = %v, want %v", err, tt.want)
+			}
+			if len(results) != 0 {
+				t.Errorf("len(results) = %d, want 0", len(results))
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package toolrun
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// RunGraph executes steps as a dependency graph.
+// Independent steps run concurrently, bounded by parallelism. A step runs
+// only after all of its dependencies completed. When a step fails and its
+// error policy aborts, no new steps are started and every step that did not
+// run records ErrDependencyFailed. Steps handled by a continue or fallback
+// policy count as completed. When the graph aborts, the compensations of
+// completed steps run in reverse completion order.
+func (r *DefaultRunner) RunGraph(ctx context.Context, steps []GraphStep, parallelism int) ([]StepResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	g, err := r.buildGraph(steps)
+	if err != nil {
+		return nil, err
+	}
+	if parallelism <= 0 || parallelism > len(steps) {
+		parallelism = len(steps)
+	}
+
+	ctx = withChainSink(ctx, nil)
+	ctx, cancel := chainContext(ctx, nil)
+	defer cancel()
+	inputs := chainInputs(ctx)
+	results := make([]StepResult, len(steps))
+	finished := make([]bool, len(steps))
+	pending := append([]int(nil), g.indegree...)
+	var ready []int
+	for i, n := range pending {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	completions := make(chan graphCompletion)
+	var order []int
+	running := 0
+	var firstErr error
+
+	for {
+		// Launch ready steps while healthy and under the parallelism bound.
+		for firstErr == nil && ctx.Err() == nil && running < parallelism && len(ready) > 0 {
+			i := ready[0]
+			ready = ready[1:]
+			scope := g.scope(i, steps[i], results)
+			scope.inputs = inputs
+			running++
+			go func(i int, scope *chainScope) {
+				var abortErr error
+				results[i], abortErr = r.runChainStep(ctx, steps[i].ChainStep, scope)
+				completions <- graphCompletion{index: i, err: abortErr}
+			}(i, scope)
+		}
+		if running == 0 {
+			break
+		}
+
+		c := <-completions
+		i := c.index
+		running--
+		finished[i] = true
+		order = append(order, i)
+		if c.err != nil {
+			if firstErr == nil {
+				firstErr = c.err
+			}
+			continue
+		}
+		for _, d := range g.dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	ctxErr := ctx.Err()
+	if cause := timeoutCause(ctx); cause != nil {
+		ctxErr = fmt.Errorf("%w: %w", cause, ctxErr)
+	}
+	for i, step := range steps {
+		if finished[i] {
+			continue
+		}
+		skipErr := ctxErr
+		if skipErr == nil || firstErr != nil {
+			skipErr = WrapError(step.ToolID, nil, "graph", ErrDependencyFailed)
+		}
+		results[i] = StepResult{ID: step.ID, ToolID: step.ToolID, Err: skipErr}
+	}
+
+	if ctxErr != nil {
+		return results, r.compensateGraph(ctx, g, steps, results, order, ctxErr)
+	}
+	if firstErr != nil {
+		return results, r.compensateGraph(ctx, g, steps, results, order, firstErr)
+	}
+	return results, nil
+}
+
+// graphCompletion reports a finished graph step to the scheduler.
+// err is non-nil when the step's error policy aborts the graph.
+type graphCompletion struct {
+	index int
+	err   error
+}
+
+// stepGraph is the validated adjacency view of a []GraphStep.
+type stepGraph struct {
+	// index maps step ID to its position in the input slice.
+	index map[string]int
+
+	// deps lists, for each step, the distinct steps it depends on.
+	deps [][]int
+
+	// dependents lists, for each step, the steps that depend on it.
+	dependents [][]int
+
+	// indegree counts the distinct dependencies of each step.
+	indegree []int
+}
+
+// buildGraph validates step IDs, dependencies, references, and error
+// policies, and rejects cycles.
+func (r *DefaultRunner) buildGraph(steps []GraphStep) (*stepGraph, error) {
+	g := &stepGraph{
+		index:      make(map[string]int, len(steps)),
+		deps:       make([][]int, len(steps)),
+		dependents: make([][]int, len(steps)),
+		indegree:   make([]int, len(steps)),
+	}
+	for i, step := range steps {
+		if step.ID == "" {
+			return nil, fmt.Errorf("%w: step %d has no id", ErrInvalidChain, i)
+		}
+		if _, dup := g.index[step.ID]; dup {
+			return nil, fmt.Errorf("%w: duplicate step id %q", ErrInvalidChain, step.ID)
+		}
+		g.index[step.ID] = i
+	}
+
+	for i, step := range steps {
+		seen := make(map[string]struct{}, len(step.DependsOn))
+		for _, dep := range step.DependsOn {
+			j, ok := g.index[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: step %q depends on unknown step %q", ErrInvalidChain, step.ID, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("%w: step %q depends on itself", ErrInvalidChain, step.ID)
+			}
+			if _, dup := seen[dep]; dup {
+				continue
+			}
+			seen[dep] = struct{}{}
+			g.deps[i] = append(g.deps[i], j)
+			g.dependents[j] = append(g.dependents[j], i)
+			g.indegree[i]++
+		}
+	}
+
+	// Kahn's algorithm: every step must be reachable from a root.
+	pending := append([]int(nil), g.indegree...)
+	var queue []int
+	for i, n := range pending {
+		if n == 0 {
+			queue = append(queue, i)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, d := range g.dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if visited != len(steps) {
+		return nil, fmt.Errorf("%w: dependency cycle detected", ErrInvalidChain)
+	}
+
+	// References may only point at ancestors, which are guaranteed complete.
+	for i, step := range steps {
+		visible := make(map[string]bool)
+		for _, a := range g.ancestors(i) {
+			visible[strconv.Itoa(a)] = true
+			visible[steps[a].ID] = true
+		}
+		if err := checkStepRefs(step.ChainStep, visible); err != nil {
+			return nil, err
+		}
+		visible[strconv.Itoa(i)] = true
+		visible[step.ID] = true
+		if err := checkCompensation(step.ChainStep, visible); err != nil {
+			return nil, err
+		}
+		policy := r.errorPolicy(step.ChainStep)
+		if err := checkErrorPolicy(&policy); err != nil {
+			return nil, WrapError(step.ToolID, nil, "check_chain", err)
+		}
+	}
+
+	return g, nil
+}
+
+// ancestors returns every step that i transitively depends on.
+func (g *stepGraph) ancestors(i int) []int {
+	seen := make(map[int]bool)
+	var out []int
+	stack := append([]int(nil), g.deps[i]...)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+		stack = append(stack, g.deps[n]...)
+	}
+	return out
+}
+
+// scope builds the reference scope for step i from its completed ancestors.
+// It must be called from the scheduling goroutine once all ancestors finished.
+func (g *stepGraph) scope(i int, step GraphStep, results []StepResult) *chainScope {
+	scope := &chainScope{steps: make([]*StepResult, len(results))}
+	for _, a := range g.ancestors(i) {
+		sr := results[a]
+		scope.steps[a] = &sr
+	}
+	scope.previous = g.previous(step, results)
+	return scope
+}
+
+// previous builds the value injected at args["previous"] for a graph step:
+// a map of dependency ID to that dependency's structured result.
+func (g *stepGraph) previous(step GraphStep, results []StepResult) any {
+	if !step.UsePrevious && len(step.Project) == 0 {
+		return nil
+	}
+	parents := make(map[string]any, len(step.DependsOn))
+	for _, dep := range step.DependsOn {
+		parents[dep] = results[g.index[dep]].Result.Structured
+	}
+	return parents
+}
+
+// Ensure DefaultRunner implements GraphRunner.
+var _ GraphRunner = (*DefaultRunner)(nil)
@@ -0,0 +1,218 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunGraph_ParallelFanIn(t *testing.T) {
+	// Both sources must be in flight at the same time to pass the barrier.
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	source := func(v string) LocalHandler {
+		return func(ctx context.Context, _ map[string]any) (any, error) {
+			barrier.Done()
+			waited := make(chan struct{})
+			go func() { barrier.Wait(); close(waited) }()
+			select {
+			case <-waited:
+			case <-time.After(2 * time.Second):
+				return nil, errors.New("sources did not run concurrently")
+			}
+			return v, nil
+		}
+	}
+
+	var mergeArgs map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"a": source("A"),
+		"b": source("B"),
+		"merge": func(_ context.Context, args map[string]any) (any, error) {
+			mergeArgs = args
+			return "merged", nil
+		},
+	})
+
+	steps := []GraphStep{
+		{ChainStep: ChainStep{ID: "a", ToolID: "a"}},
+		{ChainStep: ChainStep{ID: "b", ToolID: "b"}},
+		{ChainStep: ChainStep{ID: "merge", ToolID: "merge", UsePrevious: true}, DependsOn: []string{"a", "b"}},
+	}
+
+	results, err := runner.RunGraph(context.Background(), steps, 0)
+	if err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+	for i, id := range []string{"a", "b", "merge"} {
+		if results[i].ID != id {
+			t.Errorf("results[%d].ID = %q, want %q", i, results[i].ID, id)
+		}
+	}
+	parents, ok := mergeArgs["previous"].(map[string]any)
+	if !ok {
+		t.Fatalf("previous type = %T, want map[string]any", mergeArgs["previous"])
+	}
+	if parents["a"] != "A" || parents["b"] != "B" {
+		t.Errorf("previous = %v, want map[a:A b:B]", parents)
+	}
+	if results[2].Result.Structured != "merged" {
+		t.Errorf("merge result = %v, want merged", results[2].Result.Structured)
+	}
+}
+
+func TestRunGraph_ParallelismBound(t *testing.T) {
+	var inFlight, maxInFlight int32
+	h := func(_ context.Context, _ map[string]any) (any, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return "ok", nil
+	}
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"work": h})
+
+	var steps []GraphStep
+	for _, id := range []string{"s1", "s2", "s3", "s4", "s5"} {
+		steps = append(steps, GraphStep{ChainStep: ChainStep{ID: id, ToolID: "work"}})
+	}
+
+	if _, err := runner.RunGraph(context.Background(), steps, 2); err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("max in-flight = %d, want <= 2", got)
+	}
+}
+
+func TestRunGraph_FailureSkipsDependents(t *testing.T) {
+	var downstreamCalled atomic.Bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"after": func(_ context.Context, _ map[string]any) (any, error) {
+			downstreamCalled.Store(true)
+			return "ok", nil
+		},
+	})
+
+	steps := []GraphStep{
+		{ChainStep: ChainStep{ID: "first", ToolID: "fail"}},
+		{ChainStep: ChainStep{ID: "second", ToolID: "after"}, DependsOn: []string{"first"}},
+	}
+
+	results, err := runner.RunGraph(context.Background(), steps, 1)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("RunGraph() error = %v, want ErrExecution", err)
+	}
+	if downstreamCalled.Load() {
+		t.Error("dependent step should not run after its dependency failed")
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if !errors.Is(results[1].Err, ErrDependencyFailed) {
+		t.Errorf("results[1].Err = %v, want ErrDependencyFailed", results[1].Err)
+	}
+	if results[1].ID != "second" {
+		t.Errorf("results[1].ID = %q, want second", results[1].ID)
+	}
+}
+
+func TestRunGraph_InvalidGraphs(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"t": func(_ context.Context, _ map[string]any) (any, error) { return nil, nil },
+	})
+
+	tests := []struct {
+		name  string
+		steps []GraphStep
+	}{
+		{
+			name:  "missing id",
+			steps: []GraphStep{{ChainStep: ChainStep{ToolID: "t"}}},
+		},
+		{
+			name: "duplicate id",
+			steps: []GraphStep{
+				{ChainStep: ChainStep{ID: "x", ToolID: "t"}},
+				{ChainStep: ChainStep{ID: "x", ToolID: "t"}},
+			},
+		},
+		{
+			name:  "unknown dependency",
+			steps: []GraphStep{{ChainStep: ChainStep{ID: "x", ToolID: "t"}, DependsOn: []string{"y"}}},
+		},
+		{
+			name: "cycle",
+			steps: []GraphStep{
+				{ChainStep: ChainStep{ID: "x", ToolID: "t"}, DependsOn: []string{"y"}},
+				{ChainStep: ChainStep{ID: "y", ToolID: "t"}, DependsOn: []string{"x"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := runner.RunGraph(context.Background(), tt.steps, 0)
+			if !errors.Is(err, ErrInvalidChain) {
+				t.Errorf("RunGraph() error = %v, want ErrInvalidChain", err)
+			}
+			if results != nil {
+				t.Errorf("results = %v, want nil", results)
+			}
+		})
+	}
+}
+
+func TestRunGraph_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var secondCalled atomic.Bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"first": func(_ context.Context, _ map[string]any) (any, error) {
+			cancel()
+			return "ok", nil
+		},
+		"second": func(_ context.Context, _ map[string]any) (any, error) {
+			secondCalled.Store(true)
+			return "ok", nil
+		},
+	})
+
+	steps := []GraphStep{
+		{ChainStep: ChainStep{ID: "first", ToolID: "first"}},
+		{ChainStep: ChainStep{ID: "second", ToolID: "second"}, DependsOn: []string{"first"}},
+	}
+
+	results, err := runner.RunGraph(ctx, steps, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunGraph() error = %v, want context.Canceled", err)
+	}
+	if secondCalled.Load() {
+		t.Error("RunGraph() should not start steps after cancellation")
+	}
+	if len(results) != 2 || !errors.Is(results[1].Err, context.Canceled) {
+		t.Errorf("results[1].Err = %v, want context.Canceled", results[1].Err)
+	}
+}
+
+func TestRunGraph_Empty(t *testing.T) {
+	results, err := NewRunner().RunGraph(context.Background(), nil, 0)
+	if err != nil || results != nil {
+		t.Errorf("RunGraph(nil) = (%v, %v), want (nil, nil)", results, err)
+	}
+}
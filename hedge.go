@@ -0,0 +1,108 @@
+package toolrun
+
+import (
+	"context"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// HedgePolicy controls hedged requests: when the selected backend has not
+// answered within Delay, the same call is also sent to the next backend, and
+// the first success wins. The zero value disables hedging.
+//
+// Hedging only applies to tools whose MCP annotations mark them idempotent
+// or read-only (IdempotentHint or ReadOnlyHint), since the same call may run
+// on several backends.
+type HedgePolicy struct {
+	// Delay is how long to wait for a backend before hedging to the next.
+	// Zero disables hedging.
+	Delay time.Duration `json:"delay,omitempty"`
+
+	// MaxHedges bounds the extra calls made for one attempt. Defaults to 1.
+	MaxHedges int `json:"maxHedges,omitempty"`
+}
+
+// hedges reports whether calls to tool are hedged.
+func (r *DefaultRunner) hedges(tool toolmodel.Tool) bool {
+	if r.cfg.Hedging.Delay <= 0 {
+		return false
+	}
+	a := tool.Annotations
+	return a != nil && (a.IdempotentHint || a.ReadOnlyHint)
+}
+
+// hedgeResult is the outcome of one hedged call.
+type hedgeResult struct {
+	backend toolmodel.ToolBackend
+	result  RunResult
+	err     error
+}
+
+// runHedged makes one attempt at a hedged call. The first backend is called
+// at once; each time Delay passes without a success, the next backend is
+// called as well, up to MaxHedges extra calls. A failure moves on to the next
+// backend at once when failover allows it. The first success cancels the
+// calls still in flight. Hedged calls are not streamed, since concurrent
+// calls would interleave their events.
+func (r *DefaultRunner) runHedged(ctx context.Context, toolID string, tool toolmodel.Tool, backends []toolmodel.ToolBackend, args map[string]any, tried *[]toolmodel.ToolBackend, opts dispatchOptions) (RunResult, error) {
+	opts.sink = nil
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	maxHedges := r.cfg.Hedging.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	results := make(chan hedgeResult, len(backends))
+	next, inFlight, hedged := 0, 0, 0
+	var lastErr error
+
+	// launch starts the next backend whose circuit allows a call.
+	launch := func() bool {
+		for next < len(backends) {
+			backend := backends[next]
+			next++
+			if !r.cfg.CircuitBreakers.allow(backend) {
+				if lastErr == nil {
+					lastErr = WrapError(toolID, &backend, "circuit_open", ErrCircuitOpen)
+				}
+				continue
+			}
+			if indexBackend(*tried, backend) < 0 {
+				*tried = append(*tried, backend)
+			}
+			inFlight++
+			go func() {
+				result, err := r.runBackend(ctx, toolID, tool, backend, args, opts)
+				r.cfg.CircuitBreakers.record(ctx, backend, err)
+				results <- hedgeResult{backend: backend, result: result, err: err}
+			}()
+			return true
+		}
+		return false
+	}
+
+	launch()
+	timer := time.NewTimer(r.cfg.Hedging.Delay)
+	defer timer.Stop()
+	for inFlight > 0 {
+		select {
+		case <-timer.C:
+			if hedged < maxHedges && launch() {
+				hedged++
+				timer.Reset(r.cfg.Hedging.Delay)
+			}
+		case res := <-results:
+			inFlight--
+			if res.err == nil {
+				return res.result, nil
+			}
+			lastErr = res.err
+			if ctx.Err() == nil && r.cfg.Failover.Enabled && r.cfg.Failover.shouldFailover(res.err) {
+				launch()
+			}
+		}
+	}
+	return RunResult{}, lastErr
+}
@@ -0,0 +1,123 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newHedgeTestRunner registers "multi" with a local backend running handler
+// and a provider backend, with hedging after 10ms.
+func newHedgeTestRunner(t *testing.T, annotations *mcp.ToolAnnotations, handler LocalHandler, opts ...ConfigOption) (*DefaultRunner, *mockProviderExecutor) {
+	t.Helper()
+	idx := newMockIndex()
+	tool := testTool("multi")
+	tool.Annotations = annotations
+	mustRegisterTool(t, idx, tool, testLocalBackend("multi"))
+	mustRegisterTool(t, idx, tool, testProviderBackend("p", "multi"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("multi", handler)
+	provider := newMockProviderExecutor()
+	provider.CallToolResult = "from provider"
+	runner := NewRunner(append([]ConfigOption{
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithProviderExecutor(provider),
+		WithValidation(false, false),
+		WithHedging(HedgePolicy{Delay: 10 * time.Millisecond}),
+	}, opts...)...)
+	return runner, provider
+}
+
+// slowHandler returns after d unless canceled first, reporting cancellation
+// on canceled.
+func slowHandler(d time.Duration, canceled chan<- struct{}) LocalHandler {
+	return func(ctx context.Context, _ map[string]any) (any, error) {
+		select {
+		case <-time.After(d):
+			return "from local", nil
+		case <-ctx.Done():
+			if canceled != nil {
+				close(canceled)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestRun_Hedged(t *testing.T) {
+	canceled := make(chan struct{})
+	runner, _ := newHedgeTestRunner(t, &mcp.ToolAnnotations{ReadOnlyHint: true}, slowHandler(time.Second, canceled))
+
+	start := time.Now()
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("hedged call waited for the slow backend")
+	}
+	if result.Structured != "from provider" {
+		t.Errorf("Structured = %v, want the hedge's result", result.Structured)
+	}
+	if got := backendKinds(result.BackendsTried); len(got) != 2 || got[0] != toolmodel.BackendKindLocal {
+		t.Errorf("BackendsTried = %v, want [local provider]", got)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("losing call was not canceled")
+	}
+}
+
+func TestRun_HedgedPrimaryFast(t *testing.T) {
+	runner, provider := newHedgeTestRunner(t, &mcp.ToolAnnotations{IdempotentHint: true}, slowHandler(0, nil))
+
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != "from local" || provider.CallCount != 0 {
+		t.Errorf("result = %v with %d provider calls, want local result and no hedge", result.Structured, provider.CallCount)
+	}
+}
+
+func TestRun_HedgingRequiresAnnotations(t *testing.T) {
+	destructive := true
+	for _, annotations := range []*mcp.ToolAnnotations{nil, {DestructiveHint: &destructive}} {
+		runner, provider := newHedgeTestRunner(t, annotations, slowHandler(50*time.Millisecond, nil))
+
+		result, err := runner.Run(context.Background(), "multi", nil)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if result.Structured != "from local" || provider.CallCount != 0 {
+			t.Errorf("annotations %+v: result = %v with %d provider calls, want no hedging",
+				annotations, result.Structured, provider.CallCount)
+		}
+	}
+}
+
+func TestRun_HedgedFailure(t *testing.T) {
+	failing := func(_ context.Context, _ map[string]any) (any, error) { return nil, errTest }
+
+	// Without failover a fast failure is returned.
+	runner, provider := newHedgeTestRunner(t, &mcp.ToolAnnotations{ReadOnlyHint: true}, failing)
+	provider.CallToolErr = errTest
+	if _, err := runner.Run(context.Background(), "multi", nil); !errors.Is(err, ErrExecution) {
+		t.Errorf("Run() error = %v, want ErrExecution", err)
+	}
+
+	// With failover it moves on to the next backend at once.
+	runner, _ = newHedgeTestRunner(t, &mcp.ToolAnnotations{ReadOnlyHint: true}, failing,
+		WithHedging(HedgePolicy{Delay: time.Hour}),
+		WithFailover(FailoverPolicy{Enabled: true}))
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil || result.Structured != "from provider" {
+		t.Errorf("Run() = %v, %v; want provider result", result.Structured, err)
+	}
+}
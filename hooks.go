@@ -0,0 +1,75 @@
+package toolrun
+
+import (
+	"context"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// Hooks are typed callbacks for the phases of a tool call: resolve, select
+// backend, validate input, dispatch, normalize, and validate output. Each
+// hook may inspect its phase's data and return a replacement, or return an
+// error to fail the call; hook errors are returned as ToolErrors with Op
+// "hook". Nil hooks are skipped.
+//
+// Hooks run for every tool call, including chain steps and streams; the
+// dispatch and normalize hooks run once per backend call, so again for each
+// retry, failover, and hedged call.
+type Hooks struct {
+	// OnResolved runs after resolution with the tool and all of its
+	// backends, before a backend is selected. It may replace either.
+	OnResolved func(ctx context.Context, toolID string, tool toolmodel.Tool, backends []toolmodel.ToolBackend) (toolmodel.Tool, []toolmodel.ToolBackend, error)
+
+	// OnBackendSelected runs after backend selection, with the selected
+	// backend in call.Backend. It returns the backend to use; the tool's
+	// other backends remain failover alternates.
+	OnBackendSelected func(ctx context.Context, call Call) (toolmodel.ToolBackend, error)
+
+	// AfterValidateInput runs after input validation passes, or is disabled.
+	// It returns the args to dispatch, which are not validated again.
+	AfterValidateInput func(ctx context.Context, call Call) (map[string]any, error)
+
+	// OnValidationFailure runs when input or output validation fails, with
+	// the ToolError about to be returned (matching ErrValidation or
+	// ErrOutputValidation). It returns the error to return instead, or nil
+	// to accept the args or result anyway.
+	OnValidationFailure func(ctx context.Context, call Call, err error) error
+
+	// BeforeDispatch runs before each backend call, with the backend being
+	// called in call.Backend. It returns the args to send.
+	BeforeDispatch func(ctx context.Context, call Call) (map[string]any, error)
+
+	// AfterNormalize runs after a backend's result is normalized, before
+	// output validation. It returns the result to validate and return.
+	AfterNormalize func(ctx context.Context, call Call, result RunResult) (RunResult, error)
+}
+
+// hookError wraps an error returned by a hook.
+func hookError(call Call, err error) error {
+	var b *toolmodel.ToolBackend
+	if call.Backend.Kind != "" {
+		b = &call.Backend
+	}
+	return WrapError(call.ToolID, b, "hook", err)
+}
+
+// validationFailed passes a validation error through OnValidationFailure,
+// returning nil when the hook accepts the call anyway.
+func (r *DefaultRunner) validationFailed(ctx context.Context, call Call, err error) error {
+	if r.cfg.Hooks.OnValidationFailure == nil {
+		return err
+	}
+	return r.cfg.Hooks.OnValidationFailure(ctx, call, err)
+}
+
+// beforeDispatch applies BeforeDispatch, returning the args to send.
+func (r *DefaultRunner) beforeDispatch(ctx context.Context, call Call) (map[string]any, error) {
+	if r.cfg.Hooks.BeforeDispatch == nil {
+		return call.Args, nil
+	}
+	args, err := r.cfg.Hooks.BeforeDispatch(ctx, call)
+	if err != nil {
+		return nil, hookError(call, err)
+	}
+	return args, nil
+}
@@ -0,0 +1,229 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// newValidatingHookRunner registers "strict", which requires arg "q" and
+// returns an object, plus "bad", which returns a string despite its object
+// output schema. Input and output validation are on.
+func newValidatingHookRunner(t *testing.T, hooks Hooks, seen *map[string]any) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	strict := testToolWithOutputSchema("strict")
+	strict.InputSchema = map[string]any{"type": "object", "required": []any{"q"}}
+	mustRegisterTool(t, idx, strict, testLocalBackend("strict"))
+	mustRegisterTool(t, idx, testToolWithOutputSchema("bad"), testLocalBackend("bad"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("strict", func(_ context.Context, args map[string]any) (any, error) {
+		*seen = args
+		return map[string]any{"ok": true}, nil
+	})
+	localReg.Register("bad", func(_ context.Context, _ map[string]any) (any, error) {
+		return "not an object", nil
+	})
+	return NewRunner(WithIndex(idx), WithLocalRegistry(localReg), WithHooks(hooks))
+}
+
+func TestHooks_PhaseOrder(t *testing.T) {
+	var log []string
+	var seen map[string]any
+	runner := newValidatingHookRunner(t, Hooks{
+		OnResolved: func(_ context.Context, _ string, tool toolmodel.Tool, backends []toolmodel.ToolBackend) (toolmodel.Tool, []toolmodel.ToolBackend, error) {
+			log = append(log, "resolved")
+			return tool, backends, nil
+		},
+		OnBackendSelected: func(_ context.Context, call Call) (toolmodel.ToolBackend, error) {
+			log = append(log, "selected")
+			return call.Backend, nil
+		},
+		AfterValidateInput: func(_ context.Context, call Call) (map[string]any, error) {
+			log = append(log, "validated")
+			return call.Args, nil
+		},
+		BeforeDispatch: func(_ context.Context, call Call) (map[string]any, error) {
+			log = append(log, "dispatch")
+			return call.Args, nil
+		},
+		AfterNormalize: func(_ context.Context, _ Call, result RunResult) (RunResult, error) {
+			log = append(log, "normalized")
+			return result, nil
+		},
+	}, &seen)
+
+	if _, err := runner.Run(context.Background(), "strict", map[string]any{"q": "x"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"resolved", "selected", "validated", "dispatch", "normalized"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("phases = %v, want %v", log, want)
+	}
+}
+
+func TestHooks_OnResolvedReplacesBackends(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t, WithHooks(Hooks{
+		OnResolved: func(_ context.Context, _ string, tool toolmodel.Tool, backends []toolmodel.ToolBackend) (toolmodel.Tool, []toolmodel.ToolBackend, error) {
+			var kept []toolmodel.ToolBackend
+			for _, b := range backends {
+				if b.Kind != toolmodel.BackendKindLocal {
+					kept = append(kept, b)
+				}
+			}
+			return tool, kept, nil
+		},
+	}))
+	provider.CallToolResult = "from provider"
+
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil || result.Backend.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("Run() = %s, %v; want provider result", result.Backend.Kind, err)
+	}
+}
+
+func TestHooks_OnBackendSelected(t *testing.T) {
+	runner, _, mcpExec := newFailoverTestRunner(t, WithHooks(Hooks{
+		OnBackendSelected: func(_ context.Context, _ Call) (toolmodel.ToolBackend, error) {
+			return testMCPBackend("srv"), nil
+		},
+	}))
+	mcpExec.CallToolResult = testMCPResult("from mcp")
+
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil || result.Backend.Kind != toolmodel.BackendKindMCP {
+		t.Errorf("Run() = %s, %v; want mcp result", result.Backend.Kind, err)
+	}
+}
+
+func TestHooks_RewriteArgs(t *testing.T) {
+	var seen map[string]any
+	runner := newValidatingHookRunner(t, Hooks{
+		AfterValidateInput: func(_ context.Context, call Call) (map[string]any, error) {
+			args := maps.Clone(call.Args)
+			args["validated"] = true
+			return args, nil
+		},
+		BeforeDispatch: func(_ context.Context, call Call) (map[string]any, error) {
+			args := maps.Clone(call.Args)
+			args["backend"] = string(call.Backend.Kind)
+			return args, nil
+		},
+	}, &seen)
+
+	if _, err := runner.Run(context.Background(), "strict", map[string]any{"q": "x"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := map[string]any{"q": "x", "validated": true, "backend": "local"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("handler args = %v, want %v", seen, want)
+	}
+}
+
+func TestHooks_OnValidationFailure(t *testing.T) {
+	errRejectedInput := errors.New("rejected input")
+	var failures []error
+	var seen map[string]any
+	runner := newValidatingHookRunner(t, Hooks{
+		OnValidationFailure: func(_ context.Context, call Call, err error) error {
+			failures = append(failures, err)
+			switch {
+			case call.Args["accept"] == true:
+				return nil
+			case errors.Is(err, ErrValidation):
+				return errRejectedInput
+			}
+			return err
+		},
+	}, &seen)
+	ctx := context.Background()
+
+	if _, err := runner.Run(ctx, "strict", nil); !errors.Is(err, errRejectedInput) {
+		t.Errorf("Run() error = %v, want replaced error", err)
+	}
+	if _, err := runner.Run(ctx, "strict", map[string]any{"accept": true}); err != nil {
+		t.Errorf("Run() error = %v, want the invalid input accepted", err)
+	}
+	if _, err := runner.Run(ctx, "bad", nil); !errors.Is(err, ErrOutputValidation) {
+		t.Errorf("Run() error = %v, want ErrOutputValidation", err)
+	}
+	result, err := runner.Run(ctx, "bad", map[string]any{"accept": true})
+	if err != nil || result.Structured != "not an object" {
+		t.Errorf("Run() = %v, %v; want the invalid output accepted", result.Structured, err)
+	}
+	if len(failures) != 4 {
+		t.Errorf("OnValidationFailure called %d times, want 4", len(failures))
+	}
+}
+
+func TestHooks_AfterNormalize(t *testing.T) {
+	var seen map[string]any
+	runner := newValidatingHookRunner(t, Hooks{
+		AfterNormalize: func(_ context.Context, _ Call, result RunResult) (RunResult, error) {
+			result.Structured = map[string]any{"wrapped": result.Structured}
+			return result, nil
+		},
+	}, &seen)
+
+	result, err := runner.Run(context.Background(), "bad", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v, want the rewritten result to pass validation", err)
+	}
+	if want := map[string]any{"wrapped": "not an object"}; !reflect.DeepEqual(result.Structured, want) {
+		t.Errorf("Structured = %v, want %v", result.Structured, want)
+	}
+}
+
+func TestHooks_Error(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	var seen map[string]any
+	runner := newValidatingHookRunner(t, Hooks{
+		BeforeDispatch: func(_ context.Context, _ Call) (map[string]any, error) {
+			return nil, errBlocked
+		},
+	}, &seen)
+
+	_, err := runner.Run(context.Background(), "strict", map[string]any{"q": "x"})
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "hook" || !errors.Is(err, errBlocked) {
+		t.Errorf("Run() error = %v, want hook ToolError wrapping errBlocked", err)
+	}
+	if seen != nil {
+		t.Error("handler ran despite hook error")
+	}
+	if DefaultRetryable(err) {
+		t.Error("hook errors should not be retried")
+	}
+}
+
+func TestHooks_RunStream(t *testing.T) {
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("gen"), testProviderBackend("p", "gen"))
+	provider := newMockProviderExecutor()
+	provider.CallToolStreamChan = make(chan StreamEvent)
+	close(provider.CallToolStreamChan)
+	var dispatched bool
+	runner := NewRunner(
+		WithIndex(idx),
+		WithProviderExecutor(provider),
+		WithHooks(Hooks{
+			BeforeDispatch: func(_ context.Context, call Call) (map[string]any, error) {
+				dispatched = true
+				return call.Args, nil
+			},
+		}),
+	)
+
+	ch, err := runner.RunStream(context.Background(), "gen", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	collectEvents(ch)
+	if !dispatched {
+		t.Error("BeforeDispatch did not run for RunStream")
+	}
+}
@@ -0,0 +1,142 @@
+package toolrun
+
+import (
+	"context"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// Call describes a tool call to interceptors, after its tool has been
+// resolved and its backend selected but before its input is validated.
+// When resolution fails, interceptors still see the call, with a zero Tool
+// and Backend, and next returns the resolution error.
+//
+// Interceptors may replace Args and Backend before calling the next handler:
+// the runner validates the Args and dispatches to the Backend they leave,
+// keeping the tool's other backends as failover alternates. Args belongs to
+// the caller and must be copied rather than modified in place. ToolID and
+// Tool are informational.
+type Call struct {
+	// ToolID is the canonical tool ID the call was made with.
+	ToolID string
+
+	// Args are the call's arguments.
+	Args map[string]any
+
+	// Tool is the resolved tool definition.
+	Tool toolmodel.Tool
+
+	// Backend is the selected backend.
+	Backend toolmodel.ToolBackend
+}
+
+// RunHandler runs a call and returns its result.
+type RunHandler func(ctx context.Context, call *Call) (RunResult, error)
+
+// Interceptor wraps the execution of a call. It may inspect or change the
+// call, short-circuit by returning without calling next, and observe or
+// replace the result and error that next returns.
+//
+// Interceptors apply to every call: Run, RunWithProgress, RunStream, and
+// every tool call made by a chain, graph, or chain stream step, including
+// calls that fail to resolve or validate. For RunStream, next returns once
+// the stream has started, with a zero RunResult; an interceptor that returns
+// a result without calling next ends the stream with a single done event
+// carrying the result's Structured value. Use StreamInterceptors to see or
+// wrap RunStream's events.
+type Interceptor func(ctx context.Context, call *Call, next RunHandler) (RunResult, error)
+
+// StreamHandler starts a streaming call and returns its events.
+type StreamHandler func(ctx context.Context, call *Call) (<-chan StreamEvent, error)
+
+// StreamInterceptor wraps a streaming call made with RunStream, including
+// calls that fail to resolve or validate, outside the Interceptors. It may
+// inspect or change the call, short-circuit by returning without calling
+// next, and observe or wrap the event channel or error that next returns.
+// Only RunStream is wrapped; the streamed steps of RunChainStream go through
+// Interceptors alone.
+type StreamInterceptor func(ctx context.Context, call *Call, next StreamHandler) (<-chan StreamEvent, error)
+
+// intercept runs call through the configured interceptors, the first
+// outermost, ending with final.
+func (r *DefaultRunner) intercept(ctx context.Context, call *Call, final RunHandler) (RunResult, error) {
+	h := final
+	for i := len(r.cfg.Interceptors) - 1; i >= 0; i-- {
+		ic, next := r.cfg.Interceptors[i], h
+		h = func(ctx context.Context, call *Call) (RunResult, error) {
+			return ic(ctx, call, next)
+		}
+	}
+	return h(ctx, call)
+}
+
+// interceptStream runs call through the configured stream interceptors, the
+// first outermost, ending with final.
+func (r *DefaultRunner) interceptStream(ctx context.Context, call *Call, final StreamHandler) (<-chan StreamEvent, error) {
+	h := final
+	for i := len(r.cfg.StreamInterceptors) - 1; i >= 0; i-- {
+		ic, next := r.cfg.StreamInterceptors[i], h
+		h = func(ctx context.Context, call *Call) (<-chan StreamEvent, error) {
+			return ic(ctx, call, next)
+		}
+	}
+	return h(ctx, call)
+}
+
+// interceptStreamStart runs the start of a RunStream call through the
+// configured interceptors, ending with start. Events of a stream that start
+// opened are discarded if an interceptor then fails the call.
+func (r *DefaultRunner) interceptStreamStart(ctx context.Context, call *Call, start StreamHandler) (<-chan StreamEvent, error) {
+	var events <-chan StreamEvent
+	result, err := r.intercept(ctx, call, func(ctx context.Context, call *Call) (RunResult, error) {
+		var err error
+		events, err = start(ctx, call)
+		return RunResult{}, err
+	})
+	if err != nil {
+		if events != nil {
+			go func() {
+				for range events {
+				}
+			}()
+		}
+		return nil, err
+	}
+	if events == nil {
+		// An interceptor returned a result without starting the stream.
+		done := make(chan StreamEvent, 1)
+		done <- StreamEvent{Kind: StreamEventDone, ToolID: call.ToolID, Data: result.Structured}
+		close(done)
+		return done, nil
+	}
+	return events, nil
+}
+
+// unresolvedCall describes a call whose tool failed to resolve.
+func unresolvedCall(toolID string, args map[string]any) *Call {
+	return &Call{ToolID: toolID, Args: args}
+}
+
+// failedRun returns a RunHandler that fails with err.
+func failedRun(err error) RunHandler {
+	return func(context.Context, *Call) (RunResult, error) {
+		return RunResult{}, err
+	}
+}
+
+// preferBackend moves b to the front of backends, adding it if missing.
+func preferBackend(backends []toolmodel.ToolBackend, b toolmodel.ToolBackend) []toolmodel.ToolBackend {
+	key := backendKey(b)
+	if len(backends) > 0 && backendKey(backends[0]) == key {
+		backends[0] = b
+		return backends
+	}
+	out := make([]toolmodel.ToolBackend, 0, len(backends)+1)
+	out = append(out, b)
+	for _, candidate := range backends {
+		if backendKey(candidate) != key {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
@@ -0,0 +1,374 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// recordingInterceptor appends "<name>:before:<tool>" and
+// "<name>:after:<tool>" to log around each call.
+func recordingInterceptor(name string, log *[]string) Interceptor {
+	return func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+		*log = append(*log, name+":before:"+call.ToolID)
+		result, err := next(ctx, call)
+		*log = append(*log, name+":after:"+call.ToolID)
+		return result, err
+	}
+}
+
+func TestInterceptors_Order(t *testing.T) {
+	var log []string
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"echo": func(_ context.Context, _ map[string]any) (any, error) {
+			log = append(log, "handler")
+			return "ok", nil
+		},
+	})
+	runner.cfg.Interceptors = []Interceptor{
+		recordingInterceptor("outer", &log),
+		recordingInterceptor("inner", &log),
+	}
+
+	result, err := runner.Run(context.Background(), "echo", nil)
+	if err != nil || result.Structured != "ok" {
+		t.Fatalf("Run() = %v, %v; want ok", result.Structured, err)
+	}
+	want := []string{"outer:before:echo", "inner:before:echo", "handler", "inner:after:echo", "outer:after:echo"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestInterceptors_SeeResolvedCall(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"echo": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+	var seen Call
+	var seenErr error
+	runner.cfg.Interceptors = []Interceptor{func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+		seen = *call
+		result, err := next(ctx, call)
+		seenErr = err
+		return result, err
+	}}
+
+	args := map[string]any{"x": 1}
+	if _, err := runner.Run(context.Background(), "echo", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if seen.ToolID != "echo" || seen.Tool.Name != "echo" || seen.Backend.Kind != toolmodel.BackendKindLocal || seen.Args["x"] != 1 {
+		t.Errorf("call = %+v, want resolved echo call with args", seen)
+	}
+	if seenErr != nil {
+		t.Errorf("observed error = %v, want nil", seenErr)
+	}
+}
+
+func TestInterceptors_ShortCircuit(t *testing.T) {
+	var called bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"echo": func(_ context.Context, _ map[string]any) (any, error) {
+			called = true
+			return "ok", nil
+		},
+	})
+	errDenied := errors.New("denied")
+	runner.cfg.Interceptors = []Interceptor{func(_ context.Context, _ *Call, _ RunHandler) (RunResult, error) {
+		return RunResult{}, errDenied
+	}}
+
+	if _, err := runner.Run(context.Background(), "echo", nil); !errors.Is(err, errDenied) {
+		t.Errorf("Run() error = %v, want errDenied", err)
+	}
+	if called {
+		t.Error("handler ran despite short-circuit")
+	}
+}
+
+func TestInterceptors_MutateArgs(t *testing.T) {
+	var got map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"echo": func(_ context.Context, args map[string]any) (any, error) {
+			got = args
+			return "ok", nil
+		},
+	})
+	runner.cfg.Interceptors = []Interceptor{func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+		args := maps.Clone(call.Args)
+		args["token"] = "secret"
+		call.Args = args
+		return next(ctx, call)
+	}}
+
+	orig := map[string]any{"q": "x"}
+	if _, err := runner.Run(context.Background(), "echo", orig); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got["token"] != "secret" || got["q"] != "x" {
+		t.Errorf("handler args = %v, want injected token", got)
+	}
+	if _, ok := orig["token"]; ok {
+		t.Error("caller's args were modified")
+	}
+}
+
+func TestInterceptors_MutatedArgsAreValidated(t *testing.T) {
+	idx := newMockIndex()
+	tool := testTool("strict")
+	tool.InputSchema = map[string]any{
+		"type":     "object",
+		"required": []any{"q"},
+	}
+	mustRegisterTool(t, idx, tool, testLocalBackend("strict"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("strict", func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil })
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(true, false),
+		WithInterceptors(func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+			call.Args = map[string]any{}
+			return next(ctx, call)
+		}),
+	)
+
+	if _, err := runner.Run(context.Background(), "strict", map[string]any{"q": "x"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Run() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestInterceptors_OverrideBackend(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t, WithInterceptors(
+		func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+			call.Backend = testProviderBackend("p", "multi")
+			return next(ctx, call)
+		},
+	))
+	provider.CallToolResult = "from provider"
+
+	result, err := runner.Run(context.Background(), "multi", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Backend.Kind != toolmodel.BackendKindProvider || provider.CallCount != 1 {
+		t.Errorf("result from %s (provider calls %d), want provider", result.Backend.Kind, provider.CallCount)
+	}
+}
+
+func TestInterceptors_ChainSteps(t *testing.T) {
+	var log []string
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"a": func(_ context.Context, _ map[string]any) (any, error) { return "a", nil },
+		"b": func(_ context.Context, _ map[string]any) (any, error) { return "b", nil },
+	})
+	runner.cfg.Interceptors = []Interceptor{recordingInterceptor("i", &log)}
+	steps := []ChainStep{{ToolID: "a"}, {ToolID: "b"}}
+
+	if _, _, err := runner.RunChainWithProgress(context.Background(), steps, nil); err != nil {
+		t.Fatalf("RunChainWithProgress() error = %v", err)
+	}
+	want := []string{"i:before:a", "i:after:a", "i:before:b", "i:after:b"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestInterceptors_ChainStreamSteps(t *testing.T) {
+	stream := make(chan StreamEvent, 1)
+	stream <- StreamEvent{Kind: StreamEventDone, Data: "hello"}
+	close(stream)
+	runner := newStreamChainRunner(t, stream)
+	var log []string
+	runner.cfg.Interceptors = []Interceptor{recordingInterceptor("i", &log)}
+
+	ch, err := runner.RunChainStream(context.Background(), []ChainStep{{ToolID: "gen"}, {ToolID: "upper"}})
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	collectEvents(ch)
+	want := []string{"i:before:gen", "i:after:gen", "i:before:upper", "i:after:upper"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestInterceptors_SeeResolutionAndValidationFailures(t *testing.T) {
+	var seen []error
+	var seenKinds []toolmodel.BackendKind
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"tool": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+	runner.cfg.Validator = &mockValidator{ValidateInputErr: errTest}
+	runner.cfg.ValidateInput = true
+	runner.cfg.Interceptors = []Interceptor{func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+		result, err := next(ctx, call)
+		seen = append(seen, err)
+		seenKinds = append(seenKinds, call.Backend.Kind)
+		return result, err
+	}}
+	var streamSeen error
+	runner.cfg.StreamInterceptors = []StreamInterceptor{func(ctx context.Context, call *Call, next StreamHandler) (<-chan StreamEvent, error) {
+		ch, err := next(ctx, call)
+		streamSeen = err
+		return ch, err
+	}}
+
+	if _, err := runner.Run(context.Background(), "missing", nil); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("Run(missing) error = %v, want ErrToolNotFound", err)
+	}
+	if _, err := runner.Run(context.Background(), "tool", nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("Run(tool) error = %v, want ErrValidation", err)
+	}
+	if len(seen) != 2 || !errors.Is(seen[0], ErrToolNotFound) || !errors.Is(seen[1], ErrValidation) {
+		t.Errorf("interceptor saw %v, want the resolution then the validation error", seen)
+	}
+	if want := []toolmodel.BackendKind{"", toolmodel.BackendKindLocal}; !reflect.DeepEqual(seenKinds, want) {
+		t.Errorf("interceptor saw backends %v, want %v", seenKinds, want)
+	}
+
+	if _, err := runner.RunStream(context.Background(), "missing", nil); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("RunStream(missing) error = %v, want ErrToolNotFound", err)
+	}
+	if !errors.Is(streamSeen, ErrToolNotFound) {
+		t.Errorf("stream interceptor saw %v, want ErrToolNotFound", streamSeen)
+	}
+}
+
+func TestInterceptors_RunAndStreamChains(t *testing.T) {
+	stream := make(chan StreamEvent, 1)
+	stream <- StreamEvent{Kind: StreamEventDone, Data: "done"}
+	close(stream)
+	runner := newStreamChainRunner(t, stream)
+	var log []string
+	runner.cfg.Interceptors = []Interceptor{recordingInterceptor("run", &log)}
+	runner.cfg.StreamInterceptors = []StreamInterceptor{func(ctx context.Context, call *Call, next StreamHandler) (<-chan StreamEvent, error) {
+		log = append(log, "stream:"+call.ToolID)
+		return next(ctx, call)
+	}}
+
+	// Run, RunStream, and the streamed steps of RunChainStream go through
+	// Interceptors; RunStream also goes through StreamInterceptors, outside
+	// them.
+	if _, err := runner.Run(context.Background(), "upper", nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	collectEvents(mustRunChainStream(t, runner, []ChainStep{{ToolID: "gen"}}))
+	runner.cfg.Provider.(*mockProviderExecutor).CallToolStreamChan = make(chan StreamEvent)
+	close(runner.cfg.Provider.(*mockProviderExecutor).CallToolStreamChan)
+	ch, err := runner.RunStream(context.Background(), "gen", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	collectEvents(ch)
+
+	want := []string{
+		"run:before:upper", "run:after:upper",
+		"run:before:gen", "run:after:gen",
+		"stream:gen", "run:before:gen", "run:after:gen",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+}
+
+func TestInterceptors_RunStream(t *testing.T) {
+	newRunner := func(ic Interceptor) (*DefaultRunner, *mockProviderExecutor) {
+		stream := make(chan StreamEvent)
+		close(stream)
+		runner := newStreamChainRunner(t, stream)
+		runner.cfg.Interceptors = []Interceptor{ic}
+		return runner, runner.cfg.Provider.(*mockProviderExecutor)
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		runner, provider := newRunner(func(context.Context, *Call, RunHandler) (RunResult, error) {
+			return RunResult{}, errTest
+		})
+		if _, err := runner.RunStream(context.Background(), "gen", nil); !errors.Is(err, errTest) {
+			t.Errorf("RunStream() error = %v, want errTest", err)
+		}
+		if provider.CallCount != 0 {
+			t.Errorf("provider called %d times, want 0", provider.CallCount)
+		}
+	})
+
+	t.Run("short circuit", func(t *testing.T) {
+		runner, provider := newRunner(func(context.Context, *Call, RunHandler) (RunResult, error) {
+			return RunResult{Structured: "cached"}, nil
+		})
+		ch, err := runner.RunStream(context.Background(), "gen", nil)
+		if err != nil {
+			t.Fatalf("RunStream() error = %v", err)
+		}
+		events := collectEvents(ch)
+		if len(events) != 1 || events[0].Kind != StreamEventDone || events[0].Data != "cached" {
+			t.Errorf("events = %v, want one done event with the result", events)
+		}
+		if provider.CallCount != 0 {
+			t.Errorf("provider called %d times, want 0", provider.CallCount)
+		}
+	})
+
+	t.Run("mutate args", func(t *testing.T) {
+		runner, provider := newRunner(func(ctx context.Context, call *Call, next RunHandler) (RunResult, error) {
+			c := *call
+			c.Args = map[string]any{"auth": "ok"}
+			return next(ctx, &c)
+		})
+		ch, err := runner.RunStream(context.Background(), "gen", nil)
+		if err != nil {
+			t.Fatalf("RunStream() error = %v", err)
+		}
+		collectEvents(ch)
+		if provider.CallCount != 1 || provider.LastArgs["auth"] != "ok" {
+			t.Errorf("provider calls = %d, args = %v, want one call with the interceptor's args", provider.CallCount, provider.LastArgs)
+		}
+	})
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("gen"), testProviderBackend("p", "gen"))
+	provider := newMockProviderExecutor()
+	provider.CallToolStreamChan = make(chan StreamEvent)
+	close(provider.CallToolStreamChan)
+
+	var seen []string
+	runner := NewRunner(
+		WithIndex(idx),
+		WithProviderExecutor(provider),
+		WithValidation(false, false),
+		WithStreamInterceptors(
+			func(ctx context.Context, call *Call, next StreamHandler) (<-chan StreamEvent, error) {
+				seen = append(seen, string(call.Backend.Kind))
+				return next(ctx, call)
+			},
+			func(_ context.Context, call *Call, _ StreamHandler) (<-chan StreamEvent, error) {
+				ch := make(chan StreamEvent, 1)
+				ch <- StreamEvent{Kind: StreamEventDone, ToolID: call.ToolID, Data: "cached"}
+				close(ch)
+				return ch, nil
+			},
+		),
+	)
+
+	ch, err := runner.RunStream(context.Background(), "gen", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+	events := collectEvents(ch)
+	if len(events) != 1 || events[0].Data != "cached" {
+		t.Errorf("events = %v, want the short-circuited event", events)
+	}
+	if len(seen) != 1 || seen[0] != "provider" {
+		t.Errorf("seen = %v, want [provider]", seen)
+	}
+	if provider.CallCount != 0 {
+		t.Errorf("provider called %d times, want 0", provider.CallCount)
+	}
+}
@@ -0,0 +1,315 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// Limit bounds the calls made under one key: a tool, a namespace, an MCP
+// server, or a provider. Zero fields impose no limit.
+type Limit struct {
+	// MaxConcurrent bounds the calls in flight at once (a bulkhead).
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+
+	// Rate bounds the calls started per Per with a token bucket.
+	Rate int `json:"rate,omitempty"`
+
+	// Per is the period Rate applies to. Defaults to one second.
+	Per time.Duration `json:"per,omitempty"`
+
+	// Burst is the number of calls that may start at once after an idle
+	// period. Defaults to Rate.
+	Burst int `json:"burst,omitempty"`
+}
+
+// LimitMode selects what happens to a call over a limit.
+type LimitMode string
+
+const (
+	// LimitWait queues the call until the limit allows it or its context
+	// is done (the default).
+	LimitWait LimitMode = "wait"
+
+	// LimitReject fails the call immediately with a LimitError.
+	LimitReject LimitMode = "reject"
+)
+
+// LimitConfig configures a Limiter. Limits are keyed by tool ID, namespace,
+// MCP server name, and provider ID; a call must satisfy every limit that
+// applies to it.
+type LimitConfig struct {
+	// Tools holds limits by canonical tool ID.
+	Tools map[string]Limit `json:"tools,omitempty"`
+
+	// Namespaces holds limits shared by all tools in a namespace.
+	Namespaces map[string]Limit `json:"namespaces,omitempty"`
+
+	// MCPServers holds limits shared by all tools on an MCP server.
+	MCPServers map[string]Limit `json:"mcpServers,omitempty"`
+
+	// Providers holds limits shared by all tools of a provider.
+	Providers map[string]Limit `json:"providers,omitempty"`
+
+	// Mode selects whether calls over a limit wait or are rejected.
+	// Defaults to LimitWait; WithCallLimitMode overrides it per call.
+	Mode LimitMode `json:"mode,omitempty"`
+}
+
+// LimitScope names the kind of key a limit applies to.
+type LimitScope string
+
+const (
+	// LimitScopeTool keys a limit by canonical tool ID (LimitConfig.Tools).
+	LimitScopeTool LimitScope = "tool"
+
+	// LimitScopeNamespace keys a limit by tool namespace
+	// (LimitConfig.Namespaces).
+	LimitScopeNamespace LimitScope = "namespace"
+
+	// LimitScopeMCP keys a limit by MCP server name (LimitConfig.MCPServers).
+	LimitScopeMCP LimitScope = "mcp"
+
+	// LimitScopeProvider keys a limit by provider ID (LimitConfig.Providers).
+	LimitScopeProvider LimitScope = "provider"
+)
+
+// LimitError reports a call rejected by a Limiter. It matches
+// ErrLimitExceeded.
+type LimitError struct {
+	// Scope and Key identify the limit, for example LimitScopeProvider and
+	// "github".
+	Scope LimitScope
+	Key   string
+
+	// Reason is "concurrency" for MaxConcurrent and "rate" for Rate.
+	Reason string
+}
+
+// Error returns a description of the exceeded limit.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s limit for %s %q", ErrLimitExceeded, e.Reason, e.Scope, e.Key)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Limiter enforces concurrency and rate limits on tool calls.
+// It is safe for concurrent use.
+type Limiter struct {
+	cfg LimitConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	states map[limitKey]*limitState
+}
+
+// limitKey identifies one configured limit.
+type limitKey struct {
+	scope LimitScope
+	key   string
+}
+
+// limitState is the runtime state of one limit.
+type limitState struct {
+	limit Limit
+	sem   chan struct{}
+
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter with the given configuration.
+func NewLimiter(cfg LimitConfig) *Limiter {
+	return &Limiter{
+		cfg:    cfg,
+		now:    time.Now,
+		states: make(map[limitKey]*limitState),
+	}
+}
+
+// limitModeKey is the context key for a per-call limit mode.
+type limitModeKey struct{}
+
+// WithCallLimitMode returns a context whose calls use mode instead of
+// LimitConfig.Mode when they exceed a limit.
+func WithCallLimitMode(ctx context.Context, mode LimitMode) context.Context {
+	return context.WithValue(ctx, limitModeKey{}, mode)
+}
+
+// acquire waits for (or, in reject mode, checks) every limit that applies to
+// a call of tool through backend. The returned release function must be
+// called when the call ends. A nil receiver imposes no limits.
+func (l *Limiter) acquire(ctx context.Context, tool toolmodel.Tool, backend toolmodel.ToolBackend) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	mode := l.cfg.Mode
+	if m, ok := ctx.Value(limitModeKey{}).(LimitMode); ok {
+		mode = m
+	}
+
+	var held, taken []*limitState
+	release := func() {
+		for _, st := range held {
+			<-st.sem
+		}
+	}
+	// fail releases the slots and refunds the tokens taken for earlier keys,
+	// so that a call that never runs does not count against any limit.
+	fail := func(err error) (func(), error) {
+		release()
+		for _, st := range taken {
+			l.refund(st)
+		}
+		return nil, err
+	}
+	for _, key := range l.keys(tool, backend) {
+		st := l.state(key)
+		if st == nil {
+			continue
+		}
+		if st.sem != nil {
+			if err := st.enter(ctx, mode); err != nil {
+				return fail(limitErr(key, "concurrency", err))
+			}
+			held = append(held, st)
+		}
+		if st.limit.Rate > 0 {
+			if err := l.take(ctx, st, mode); err != nil {
+				return fail(limitErr(key, "rate", err))
+			}
+			taken = append(taken, st)
+		}
+	}
+	return release, nil
+}
+
+// keys lists the limit keys that apply to a call, broadest last.
+func (l *Limiter) keys(tool toolmodel.Tool, backend toolmodel.ToolBackend) []limitKey {
+	keys := []limitKey{{LimitScopeTool, tool.ToolID()}}
+	if tool.Namespace != "" {
+		keys = append(keys, limitKey{LimitScopeNamespace, tool.Namespace})
+	}
+	switch {
+	case backend.Kind == toolmodel.BackendKindMCP && backend.MCP != nil:
+		keys = append(keys, limitKey{LimitScopeMCP, backend.MCP.ServerName})
+	case backend.Kind == toolmodel.BackendKindProvider && backend.Provider != nil:
+		keys = append(keys, limitKey{LimitScopeProvider, backend.Provider.ProviderID})
+	}
+	return keys
+}
+
+// state returns the runtime state of a configured limit, or nil when no
+// limit is configured for key.
+func (l *Limiter) state(key limitKey) *limitState {
+	var limits map[string]Limit
+	switch key.scope {
+	case LimitScopeTool:
+		limits = l.cfg.Tools
+	case LimitScopeNamespace:
+		limits = l.cfg.Namespaces
+	case LimitScopeMCP:
+		limits = l.cfg.MCPServers
+	case LimitScopeProvider:
+		limits = l.cfg.Providers
+	}
+	limit, ok := limits[key.key]
+	if !ok || (limit.MaxConcurrent <= 0 && limit.Rate <= 0) {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.states[key]
+	if !ok {
+		if limit.Per <= 0 {
+			limit.Per = time.Second
+		}
+		if limit.Burst <= 0 {
+			limit.Burst = limit.Rate
+		}
+		st = &limitState{limit: limit, tokens: float64(limit.Burst), last: l.now()}
+		if limit.MaxConcurrent > 0 {
+			st.sem = make(chan struct{}, limit.MaxConcurrent)
+		}
+		l.states[key] = st
+	}
+	return st
+}
+
+// enter takes a concurrency slot.
+func (st *limitState) enter(ctx context.Context, mode LimitMode) error {
+	select {
+	case st.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if mode == LimitReject {
+		return errRejected
+	}
+	select {
+	case st.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// take takes a token from the rate limit's bucket. In wait mode a token is
+// reserved ahead of time and the call sleeps until it is due; the reservation
+// is returned if ctx ends first.
+func (l *Limiter) take(ctx context.Context, st *limitState, mode LimitMode) error {
+	l.mu.Lock()
+	now := l.now()
+	perToken := float64(st.limit.Per) / float64(st.limit.Rate)
+	st.tokens = min(float64(st.limit.Burst), st.tokens+float64(now.Sub(st.last))/perToken)
+	st.last = now
+	if st.tokens >= 1 {
+		st.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	if mode == LimitReject {
+		l.mu.Unlock()
+		return errRejected
+	}
+	st.tokens--
+	delay := time.Duration(-st.tokens * perToken)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(st)
+		return ctx.Err()
+	}
+}
+
+// refund returns a token taken from the rate limit's bucket.
+func (l *Limiter) refund(st *limitState) {
+	l.mu.Lock()
+	st.tokens = min(float64(st.limit.Burst), st.tokens+1)
+	l.mu.Unlock()
+}
+
+// errRejected marks a limit check that failed in reject mode.
+var errRejected = errors.New("rejected")
+
+// limitErr converts a failed limit check into the error returned to callers:
+// a LimitError when rejected, or the context error when waiting ended early.
+func limitErr(key limitKey, reason string, err error) error {
+	if err == errRejected {
+		return &LimitError{Scope: key.scope, Key: key.key, Reason: reason}
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingHandler signals entered and blocks until release is closed.
+func blockingHandler(entered chan<- struct{}, release <-chan struct{}) LocalHandler {
+	return func(_ context.Context, _ map[string]any) (any, error) {
+		entered <- struct{}{}
+		<-release
+		return "done", nil
+	}
+}
+
+func TestLimiter_ConcurrencyReject(t *testing.T) {
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"slow": blockingHandler(entered, release)})
+	runner.cfg.Limiter = NewLimiter(LimitConfig{
+		Tools: map[string]Limit{"slow": {MaxConcurrent: 1}},
+		Mode:  LimitReject,
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := runner.Run(context.Background(), "slow", nil)
+		done <- err
+	}()
+	<-entered
+
+	_, err := runner.Run(context.Background(), "slow", nil)
+	var limitErr *LimitError
+	if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &limitErr) {
+		t.Fatalf("Run() error = %v, want LimitError", err)
+	}
+	if limitErr.Scope != LimitScopeTool || limitErr.Key != "slow" || limitErr.Reason != "concurrency" {
+		t.Errorf("LimitError = %+v, want tool slow concurrency", limitErr)
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "limit" {
+		t.Errorf("error = %v, want ToolError with op limit", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first Run() error = %v", err)
+	}
+	// The slot is released after the call.
+	go func() { <-entered }()
+	if _, err := runner.Run(context.Background(), "slow", nil); err != nil {
+		t.Errorf("Run() after release error = %v", err)
+	}
+}
+
+func TestLimiter_ConcurrencyWait(t *testing.T) {
+	entered, release := make(chan struct{}, 2), make(chan struct{})
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"slow": blockingHandler(entered, release)})
+	runner.cfg.Limiter = NewLimiter(LimitConfig{Tools: map[string]Limit{"slow": {MaxConcurrent: 1}}})
+
+	done := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := runner.Run(context.Background(), "slow", nil)
+			done <- err
+		}()
+	}
+	<-entered
+	select {
+	case <-entered:
+		t.Fatal("second call entered while the first held the only slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}
+
+	// Waiting ends with the caller's context.
+	entered2, release2 := make(chan struct{}, 1), make(chan struct{})
+	defer close(release2)
+	runner = newLocalTestRunner(t, map[string]LocalHandler{"slow": blockingHandler(entered2, release2)})
+	runner.cfg.Limiter = NewLimiter(LimitConfig{Tools: map[string]Limit{"slow": {MaxConcurrent: 1}}})
+	go func() { _, _ = runner.Run(context.Background(), "slow", nil) }()
+	<-entered2
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := runner.Run(ctx, "slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimiter_RateReject(t *testing.T) {
+	runner, provider, _ := newFailoverTestRunner(t)
+	provider.CallToolResult = "ok"
+	runner.cfg.Limiter = NewLimiter(LimitConfig{
+		Providers: map[string]Limit{"p": {Rate: 1, Per: time.Hour}},
+	})
+
+	ctx := WithCallLimitMode(context.Background(), LimitReject)
+	call := func() error {
+		_, err := runner.runBackend(ctx, "multi", testTool("multi"), testProviderBackend("p", "multi"), nil, dispatchOptions{})
+		return err
+	}
+	if err := call(); err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+	var limitErr *LimitError
+	if err := call(); !errors.As(err, &limitErr) || limitErr.Scope != LimitScopeProvider || limitErr.Reason != "rate" {
+		t.Errorf("second call error = %v, want provider rate LimitError", err)
+	}
+}
+
+func TestLimiter_RateWait(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fast": func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil },
+	})
+	runner.cfg.Limiter = NewLimiter(LimitConfig{
+		Tools: map[string]Limit{"fast": {Rate: 1, Per: 20 * time.Millisecond}},
+	})
+
+	start := time.Now()
+	for range 3 {
+		if _, err := runner.Run(context.Background(), "fast", nil); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("3 calls at 1 per 20ms took %v, want at least ~40ms", elapsed)
+	}
+}
+
+func TestLimiter_Namespace(t *testing.T) {
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	for _, name := range []string{"a", "b"} {
+		mustRegisterTool(t, idx, testToolWithNamespace("ns", name), testLocalBackend(name))
+		localReg.Register(name, func(_ context.Context, _ map[string]any) (any, error) { return "ok", nil })
+	}
+	runner := NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithLimiter(NewLimiter(LimitConfig{
+			Namespaces: map[string]Limit{"ns": {Rate: 1, Per: time.Hour}},
+			Mode:       LimitReject,
+		})),
+	)
+
+	if _, err := runner.Run(context.Background(), "ns:a", nil); err != nil {
+		t.Fatalf("Run(ns:a) error = %v", err)
+	}
+	if _, err := runner.Run(context.Background(), "ns:b", nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Run(ns:b) error = %v, want ErrLimitExceeded from the shared namespace limit", err)
+	}
+}
+
+func TestLimiter_RefundsRateOnReject(t *testing.T) {
+	l := NewLimiter(LimitConfig{
+		Tools:     map[string]Limit{"multi": {Rate: 1, Per: time.Hour}},
+		Providers: map[string]Limit{"p": {Rate: 1, Per: time.Hour}},
+		Mode:      LimitReject,
+	})
+	backend := testProviderBackend("p", "multi")
+
+	// Use up the provider's only token with another tool.
+	release, err := l.acquire(context.Background(), testTool("other"), backend)
+	if err != nil {
+		t.Fatalf("acquire(other) error = %v", err)
+	}
+	release()
+
+	// The tool's token is taken before the provider rejects the call, and
+	// must be refunded.
+	if _, err := l.acquire(context.Background(), testTool("multi"), backend); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("acquire(multi) error = %v, want ErrLimitExceeded", err)
+	}
+	if tokens := l.state(limitKey{LimitScopeTool, "multi"}).tokens; tokens < 1 {
+		t.Errorf("tool tokens = %v after a rejected call, want 1", tokens)
+	}
+}
+
+func TestLimiter_Stream(t *testing.T) {
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("gen"), testProviderBackend("p", "gen"))
+	provider := newMockProviderExecutor()
+	runner := NewRunner(
+		WithIndex(idx),
+		WithProviderExecutor(provider),
+		WithValidation(false, false),
+		WithLimiter(NewLimiter(LimitConfig{
+			Tools: map[string]Limit{"gen": {MaxConcurrent: 1}},
+			Mode:  LimitReject,
+		})),
+	)
+
+	stream := make(chan StreamEvent)
+	provider.CallToolStreamChan = stream
+	ch, err := runner.RunStream(context.Background(), "gen", nil)
+	if err != nil {
+		t.Fatalf("RunStream() error = %v", err)
+	}
+
+	// The slot is held while the stream is open, for streams and streamed
+	// chain steps alike.
+	if _, err := runner.RunStream(context.Background(), "gen", nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("second RunStream() error = %v, want ErrLimitExceeded", err)
+	}
+	if _, _, err := runner.RunChain(context.Background(), []ChainStep{{ToolID: "gen"}}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("RunChain() error = %v, want ErrLimitExceeded", err)
+	}
+	chainEvents, err := runner.RunChainStream(context.Background(), []ChainStep{{ToolID: "gen"}})
+	if err != nil {
+		t.Fatalf("RunChainStream() error = %v", err)
+	}
+	events := collectEvents(chainEvents)
+	if last := events[len(events)-1]; !errors.Is(last.Err, ErrLimitExceeded) {
+		t.Errorf("RunChainStream() last event = %+v, want ErrLimitExceeded", last)
+	}
+
+	close(stream)
+	collectEvents(ch)
+	done := make(chan StreamEvent, 1)
+	done <- StreamEvent{Kind: StreamEventDone, Data: "ok"}
+	close(done)
+	provider.CallToolStreamChan = done
+	if _, err := runner.RunStream(context.Background(), "gen", nil); err != nil {
+		t.Errorf("RunStream() after the stream closed error = %v", err)
+	}
+}
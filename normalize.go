@@ -14,6 +14,7 @@ func (r *DefaultRunner) normalize(tool toolmodel.Tool, backend toolmodel.ToolBac
 	result := RunResult{
 		Tool:    tool,
 		Backend: backend,
+		Steps:   dr.steps,
 	}
 
 	if dr.mcpResult != nil {
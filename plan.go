@@ -0,0 +1,251 @@
+package toolrun
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// ChainPlan describes how a chain would run, without running it.
+// It is safe to serialize to JSON.
+type ChainPlan struct {
+	// Steps holds one plan per chain step, in chain order.
+	Steps []StepPlan `json:"steps"`
+
+	// Valid is true when neither the chain nor any step has problems.
+	Valid bool `json:"valid"`
+
+	// Errors lists chain-level problems, such as invalid references or
+	// error policies.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// StepPlan describes how a single chain step would run.
+type StepPlan struct {
+	// Index is the step's position in the chain.
+	Index int `json:"index"`
+
+	// ID is the step ID, when the step declared one.
+	ID string `json:"id,omitempty"`
+
+	// ToolID is the canonical tool identifier the step calls.
+	ToolID string `json:"toolId"`
+
+	// Tool is the resolved tool definition. Nil if resolution failed.
+	Tool *toolmodel.Tool `json:"tool,omitempty"`
+
+	// Backends are all backends available for the tool.
+	Backends []toolmodel.ToolBackend `json:"backends,omitempty"`
+
+	// Backend is the backend a call would be dispatched to first: the
+	// BackendSelector's pick among the backends whose circuit is not open.
+	Backend *toolmodel.ToolBackend `json:"backend,omitempty"`
+
+	// StaticArgs are the args whose values are known before the chain runs.
+	StaticArgs map[string]any `json:"staticArgs,omitempty"`
+
+	// DynamicArgs are the args filled at run time, with their unresolved
+	// values. UsePrevious and ForEach args appear as "$.previous" and
+	// "$.item", and projected args as paths under "$.previous".
+	DynamicArgs map[string]any `json:"dynamicArgs,omitempty"`
+
+	// DependsOn lists the data the step reads at run time: earlier steps
+	// (such as "steps.fetch" or "steps[0]"), "previous", "inputs", and "item".
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// When is the step's condition, if any.
+	When string `json:"when,omitempty"`
+
+	// ForEach is the step's ForEach path, if any.
+	ForEach string `json:"forEach,omitempty"`
+
+	// Errors lists problems that would make the step fail, such as tools
+	// that do not resolve or static args that fail validation.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PlanChain describes how a chain would run without calling any backend.
+// Every step's tool and backends are resolved concurrently, and each step
+// reports the backend the BackendSelector would pick, which args are known
+// statically and which depend on earlier outputs, and the problems
+// CheckChain would report. The returned error is only set when ctx is done;
+// validation problems are recorded in the plan.
+func (r *DefaultRunner) PlanChain(ctx context.Context, steps []ChainStep) (*ChainPlan, error) {
+	plan, _, err := r.planChain(ctx, steps)
+	return plan, err
+}
+
+// planChain builds a chain plan and returns the problems found, each
+// labeled with its step.
+func (r *DefaultRunner) planChain(ctx context.Context, steps []ChainStep) (*ChainPlan, []error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+	plan := &ChainPlan{Steps: make([]StepPlan, len(steps))}
+	if err := r.checkChain(steps); err != nil {
+		errs = append(errs, err)
+		plan.Errors = append(plan.Errors, err.Error())
+	}
+
+	tools := make([]*toolmodel.Tool, len(steps))
+	stepErrs := make([][]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func(i int, step ChainStep) {
+			defer wg.Done()
+			plan.Steps[i], tools[i], stepErrs[i] = r.planStep(ctx, i, step)
+		}(i, step)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for i := 1; i < len(steps); i++ {
+		// A conditional producer may be skipped, in which case previous comes
+		// from an earlier step; such pairs are not checked.
+		if !steps[i].UsePrevious || steps[i-1].When != "" || tools[i] == nil || tools[i-1] == nil {
+			continue
+		}
+		if err := checkPreviousCompat(steps[i-1], tools[i-1], tools[i]); err != nil {
+			stepErrs[i] = append(stepErrs[i], WrapError(steps[i].ToolID, nil, "check_chain", err))
+		}
+	}
+
+	for i, step := range steps {
+		for _, err := range stepErrs[i] {
+			plan.Steps[i].Errors = append(plan.Steps[i].Errors, err.Error())
+			errs = append(errs, stepError(i, step, err))
+		}
+	}
+	plan.Valid = len(errs) == 0
+	return plan, errs, nil
+}
+
+// planStep resolves a step's tools and describes its inputs. It returns the
+// resolved tool (nil if resolution failed) and the step's problems.
+func (r *DefaultRunner) planStep(ctx context.Context, i int, step ChainStep) (StepPlan, *toolmodel.Tool, []error) {
+	sp := StepPlan{
+		Index:     i,
+		ID:        step.ID,
+		ToolID:    step.ToolID,
+		When:      step.When,
+		ForEach:   step.ForEach,
+		DependsOn: stepSources(step),
+	}
+
+	static, dynamic := splitStaticArgs(step)
+	if len(static) > 0 {
+		sp.StaticArgs = static
+	}
+	for name := range dynamic {
+		if sp.DynamicArgs == nil {
+			sp.DynamicArgs = make(map[string]any, len(dynamic))
+		}
+		sp.DynamicArgs[name] = step.Args[name]
+	}
+	if step.UsePrevious {
+		sp.DynamicArgs["previous"] = "$.previous"
+	}
+	for name, sel := range step.Project {
+		sp.DynamicArgs[name] = projectRef(sel)
+	}
+	if step.ForEach != "" {
+		itemArg := step.ItemArg
+		if itemArg == "" {
+			itemArg = defaultItemArg
+		}
+		sp.DynamicArgs[itemArg] = "$.item"
+	}
+
+	var errs []error
+	var tool *toolmodel.Tool
+	resolved, err := r.resolveTool(ctx, step.ToolID)
+	if err != nil {
+		errs = append(errs, WrapError(step.ToolID, nil, "resolve", err))
+	} else {
+		tool = &resolved.tool
+		sp.Tool = tool
+		sp.Backends = resolved.backends
+		// Order the backends as a call would, skipping open circuits.
+		if ordered, err := r.orderBackends(resolved.tool, resolved.backends); err != nil {
+			errs = append(errs, WrapError(step.ToolID, nil, "select_backend", err))
+		} else {
+			sp.Backend = &ordered[0]
+		}
+		if err := r.checkStaticArgs(step, tool); err != nil {
+			errs = append(errs, WrapError(step.ToolID, nil, "validate_input", err))
+		}
+	}
+
+	for _, extra := range auxToolIDs(step) {
+		if _, err := r.resolveTool(ctx, extra); err != nil {
+			errs = append(errs, WrapError(extra, nil, "resolve", err))
+		}
+	}
+	return sp, tool, errs
+}
+
+// stepSources lists the run-time data a step reads through its args, When
+// condition, ForEach path, and UsePrevious, in first-use order.
+func stepSources(step ChainStep) []string {
+	var paths [][]pathSegment
+	var walk func(v any)
+	walk = func(v any) {
+		switch val := v.(type) {
+		case string:
+			if expr, ok := refExpr(val); ok {
+				if segs, err := parsePath(expr); err == nil {
+					paths = append(paths, segs)
+				}
+			}
+		case map[string]any:
+			for _, k := range slices.Sorted(maps.Keys(val)) {
+				walk(val[k])
+			}
+		case []any:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+	walk(step.Args)
+	if step.When != "" {
+		if cond, err := compileCondition(step.When); err == nil {
+			paths = append(paths, cond.paths()...)
+		}
+	}
+	if step.ForEach != "" {
+		if segs, err := parsePath(step.ForEach); err == nil {
+			paths = append(paths, segs)
+		}
+	}
+
+	var sources []string
+	add := func(s string) {
+		if !slices.Contains(sources, s) {
+			sources = append(sources, s)
+		}
+	}
+	if step.UsePrevious || len(step.Project) > 0 {
+		add("previous")
+	}
+	for _, segs := range paths {
+		root := segs[0]
+		if root.isIndex {
+			continue
+		}
+		if root.key == "steps" && len(segs) > 1 {
+			add("steps" + segs[1].String())
+			continue
+		}
+		add(root.key)
+	}
+	return sources
+}
@@ -0,0 +1,131 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+func TestPlanChain(t *testing.T) {
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testTool("fetch"), testMCPBackend("srv"))
+	mustRegisterTool(t, idx, testTool("fetch"), testLocalBackend("fetch"))
+	mustRegisterTool(t, idx, testTool("summarize"), testProviderBackend("p", "sum"))
+	mcpExec := newMockMCPExecutor()
+	provider := newMockProviderExecutor()
+	runner := NewRunner(WithIndex(idx), WithMCPExecutor(mcpExec), WithProviderExecutor(provider))
+
+	steps := []ChainStep{
+		{ID: "fetch", ToolID: "fetch", Args: map[string]any{"repo": "toolrun", "n": "$.inputs.n"}},
+		{ToolID: "summarize", UsePrevious: true, When: "steps.fetch.structured.ok == true",
+			Args: map[string]any{"title": "{{steps.fetch.structured.title}}"}},
+	}
+
+	plan, err := runner.PlanChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	if !plan.Valid || len(plan.Errors) != 0 {
+		t.Fatalf("plan = %+v, want valid", plan)
+	}
+	if mcpExec.CallCount != 0 || provider.CallCount != 0 {
+		t.Error("PlanChain() must not call any backend")
+	}
+
+	first := plan.Steps[0]
+	if len(first.Backends) != 2 || first.Backend == nil || first.Backend.Kind != toolmodel.BackendKindLocal {
+		t.Errorf("Steps[0] backends = %+v, selected = %+v, want 2 with local selected", first.Backends, first.Backend)
+	}
+	if !reflect.DeepEqual(first.StaticArgs, map[string]any{"repo": "toolrun"}) {
+		t.Errorf("Steps[0].StaticArgs = %v", first.StaticArgs)
+	}
+	if !reflect.DeepEqual(first.DynamicArgs, map[string]any{"n": "$.inputs.n"}) {
+		t.Errorf("Steps[0].DynamicArgs = %v", first.DynamicArgs)
+	}
+	if !reflect.DeepEqual(first.DependsOn, []string{"inputs"}) {
+		t.Errorf("Steps[0].DependsOn = %v", first.DependsOn)
+	}
+
+	second := plan.Steps[1]
+	wantDynamic := map[string]any{"title": "{{steps.fetch.structured.title}}", "previous": "$.previous"}
+	if !reflect.DeepEqual(second.DynamicArgs, wantDynamic) {
+		t.Errorf("Steps[1].DynamicArgs = %v, want %v", second.DynamicArgs, wantDynamic)
+	}
+	if !reflect.DeepEqual(second.DependsOn, []string{"previous", "steps.fetch"}) {
+		t.Errorf("Steps[1].DependsOn = %v", second.DependsOn)
+	}
+	if second.Backend == nil || second.Backend.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("Steps[1].Backend = %+v, want provider", second.Backend)
+	}
+
+	if _, err := json.Marshal(plan); err != nil {
+		t.Errorf("json.Marshal(plan) error = %v", err)
+	}
+}
+
+func TestPlanChain_SkipsOpenCircuit(t *testing.T) {
+	breakers, _ := newTestBreakers(1)
+	runner, _, _ := newFailoverTestRunner(t, WithCircuitBreakers(breakers))
+	steps := []ChainStep{{ToolID: "multi"}}
+
+	plan, err := runner.PlanChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	if b := plan.Steps[0].Backend; b == nil || b.Kind != toolmodel.BackendKindLocal {
+		t.Fatalf("Backend = %+v, want local", b)
+	}
+
+	// A failed call opens the local circuit, so calls now go to the provider.
+	_, _ = runner.Run(context.Background(), "multi", nil)
+
+	plan, err = runner.PlanChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	if b := plan.Steps[0].Backend; b == nil || b.Kind != toolmodel.BackendKindProvider {
+		t.Errorf("Backend = %+v, want provider once the local circuit is open", b)
+	}
+	if len(plan.Steps[0].Backends) != 3 {
+		t.Errorf("Backends = %+v, want all three backends", plan.Steps[0].Backends)
+	}
+}
+
+func TestPlanChain_RecordsProblems(t *testing.T) {
+	runner := newSchemaTestRunner(t, map[string][2]any{
+		"t": {map[string]any{"type": "object", "required": []any{"x"}}, nil},
+	})
+
+	steps := []ChainStep{
+		{ToolID: "t"},
+		{ToolID: "missing", Args: map[string]any{"y": "$.steps.later.result"}},
+	}
+	plan, err := runner.PlanChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	if plan.Valid {
+		t.Error("plan.Valid = true, want false")
+	}
+	if len(plan.Errors) != 1 {
+		t.Errorf("plan.Errors = %v, want the invalid reference", plan.Errors)
+	}
+	if len(plan.Steps[0].Errors) != 1 || len(plan.Steps[1].Errors) != 1 {
+		t.Errorf("step errors = %v / %v, want one each", plan.Steps[0].Errors, plan.Steps[1].Errors)
+	}
+	if plan.Steps[1].Tool != nil {
+		t.Error("Steps[1].Tool should be nil for an unresolved tool")
+	}
+}
+
+func TestPlanChain_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewRunner().PlanChain(ctx, []ChainStep{{ToolID: "t"}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("PlanChain() error = %v, want context.Canceled", err)
+	}
+}
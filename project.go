@@ -0,0 +1,93 @@
+package toolrun
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// parseSelector parses a ChainStep.Project selector. Selectors starting with
+// "/" are JSON pointers (RFC 6901); others are paths such as "items[0].id".
+// "" and "$" select the whole value.
+func parseSelector(sel string) ([]pathSegment, error) {
+	if strings.HasPrefix(sel, "/") {
+		return parsePointer(sel)
+	}
+	if t := strings.TrimSpace(sel); t == "" || t == "$" {
+		return nil, nil
+	}
+	return parsePath(sel)
+}
+
+// parsePointer parses a JSON pointer such as "/items/0/id". Each reference
+// token is a key; walkPath treats numeric keys as indices on arrays.
+func parsePointer(ptr string) ([]pathSegment, error) {
+	tokens := strings.Split(ptr[1:], "/")
+	segs := make([]pathSegment, len(tokens))
+	for i, tok := range tokens {
+		var b strings.Builder
+		for j := 0; j < len(tok); j++ {
+			if tok[j] != '~' {
+				b.WriteByte(tok[j])
+				continue
+			}
+			if j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1') {
+				return nil, fmt.Errorf("invalid escape in %q", tok)
+			}
+			if tok[j+1] == '0' {
+				b.WriteByte('~')
+			} else {
+				b.WriteByte('/')
+			}
+			j++
+		}
+		segs[i] = pathSegment{key: b.String()}
+	}
+	return segs, nil
+}
+
+// checkProject statically validates a step's projection.
+func checkProject(step ChainStep) error {
+	for _, name := range slices.Sorted(maps.Keys(step.Project)) {
+		if name == "" {
+			return WrapError(step.ToolID, nil, "project", fmt.Errorf("%w: empty arg name", ErrInvalidReference))
+		}
+		if _, err := parseSelector(step.Project[name]); err != nil {
+			return WrapError(step.ToolID, nil, "project",
+				fmt.Errorf("%w: %s: %q: %v", ErrInvalidReference, name, step.Project[name], err))
+		}
+	}
+	return nil
+}
+
+// project selects values from previous into args per the step's projection.
+func project(step ChainStep, previous any, args map[string]any) error {
+	for name, sel := range step.Project {
+		segs, err := parseSelector(sel)
+		if err != nil {
+			return fmt.Errorf("%w: project %s: %q: %v", ErrInvalidReference, name, sel, err)
+		}
+		v, err := walkPath(previous, segs)
+		if err != nil {
+			return fmt.Errorf("%w: project %s: %q: %v", ErrInvalidReference, name, sel, err)
+		}
+		args[name] = v
+	}
+	return nil
+}
+
+// projectRef renders a selector as the reference it is equivalent to, for
+// ChainPlan. Unparseable selectors are returned as-is.
+func projectRef(sel string) string {
+	segs, err := parseSelector(sel)
+	if err != nil {
+		return sel
+	}
+	var b strings.Builder
+	b.WriteString("$.previous")
+	for _, seg := range segs {
+		b.WriteString(seg.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newProjectTestRunner registers "search", returning a large payload, and
+// "show", which echoes its args.
+func newProjectTestRunner(t *testing.T) *DefaultRunner {
+	t.Helper()
+	return newLocalTestRunner(t, map[string]LocalHandler{
+		"search": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{
+				"total": 2,
+				"items": []any{
+					map[string]any{"id": "a1", "title": "first"},
+					map[string]any{"id": "b2", "title": "second"},
+				},
+				"a/b": "slash",
+			}, nil
+		},
+		"show": func(_ context.Context, args map[string]any) (any, error) {
+			return args, nil
+		},
+	})
+}
+
+func TestRunChain_Project(t *testing.T) {
+	runner := newProjectTestRunner(t)
+
+	final, _, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "search"},
+		{ToolID: "show", Args: map[string]any{"limit": 5, "id": "overwritten"}, Project: map[string]string{
+			"id":    "items[0].id",
+			"title": "/items/1/title",
+			"slash": "/a~1b",
+			"all":   "$",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	got := final.Structured.(map[string]any)
+	if got["id"] != "a1" || got["title"] != "second" || got["slash"] != "slash" || got["limit"] != 5 {
+		t.Errorf("args = %v, want projected id, title, slash and static limit", got)
+	}
+	if all, ok := got["all"].(map[string]any); !ok || all["total"] != 2 {
+		t.Errorf("all = %v, want the whole previous value", got["all"])
+	}
+	if _, ok := got["previous"]; ok {
+		t.Error("previous should not be injected without UsePrevious")
+	}
+}
+
+func TestRunChain_ProjectMissing(t *testing.T) {
+	runner := newProjectTestRunner(t)
+
+	_, _, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "search"},
+		{ToolID: "show", Project: map[string]string{"id": "items[5].id"}},
+	})
+	if !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("RunChain() error = %v, want ErrInvalidReference", err)
+	}
+}
+
+func TestRunChain_ProjectInvalid(t *testing.T) {
+	runner := newProjectTestRunner(t)
+
+	for _, sel := range []string{"items[", "/a~2"} {
+		_, results, err := runner.RunChain(context.Background(), []ChainStep{
+			{ToolID: "search"},
+			{ToolID: "show", Project: map[string]string{"id": sel}},
+		})
+		if !errors.Is(err, ErrInvalidReference) || results != nil {
+			t.Errorf("selector %q: RunChain() = %v, %v; want ErrInvalidReference before running", sel, results, err)
+		}
+	}
+}
+
+func TestRunGraph_Project(t *testing.T) {
+	runner := newProjectTestRunner(t)
+
+	results, err := runner.RunGraph(context.Background(), []GraphStep{
+		{ChainStep: ChainStep{ID: "s", ToolID: "search"}},
+		{ChainStep: ChainStep{ID: "v", ToolID: "show", Project: map[string]string{"n": "s.total"}}, DependsOn: []string{"s"}},
+	}, 0)
+	if err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if got := results[1].Result.Structured.(map[string]any)["n"]; got != 2 {
+		t.Errorf("n = %v, want 2", got)
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	segs, err := parseSelector("/a~0b/c~1d/0")
+	if err != nil {
+		t.Fatalf("parseSelector() error = %v", err)
+	}
+	want := []pathSegment{{key: "a~b"}, {key: "c/d"}, {key: "0"}}
+	if !reflect.DeepEqual(segs, want) {
+		t.Errorf("parseSelector() = %+v, want %+v", segs, want)
+	}
+	for _, bad := range []string{"/a~", "/~~01"} {
+		if _, err := parseSelector(bad); err == nil {
+			t.Errorf("parseSelector(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestPlanChain_Project(t *testing.T) {
+	runner := newProjectTestRunner(t)
+
+	plan, err := runner.PlanChain(context.Background(), []ChainStep{
+		{ToolID: "search"},
+		{ToolID: "show", Project: map[string]string{"id": "/items/0/id"}},
+	})
+	if err != nil {
+		t.Fatalf("PlanChain() error = %v", err)
+	}
+	sp := plan.Steps[1]
+	if sp.DynamicArgs["id"] != "$.previous.items.0.id" {
+		t.Errorf("DynamicArgs = %v, want id from $.previous.items.0.id", sp.DynamicArgs)
+	}
+	if !reflect.DeepEqual(sp.DependsOn, []string{"previous"}) {
+		t.Errorf("DependsOn = %v, want [previous]", sp.DependsOn)
+	}
+}
@@ -0,0 +1,421 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// errPathMissing marks lookups that failed because the data has no value at
+// the path, as opposed to malformed paths. Conditions treat it as null.
+var errPathMissing = errors.New("no value at path")
+
+// chainInputsKey is the context key for chain inputs.
+type chainInputsKey struct{}
+
+// WithChainInputs returns a context carrying inputs for chains run with it.
+// Steps reference them as "inputs.<name>" in args and When conditions.
+func WithChainInputs(ctx context.Context, inputs map[string]any) context.Context {
+	return context.WithValue(ctx, chainInputsKey{}, inputs)
+}
+
+// chainInputs returns the inputs attached with WithChainInputs, if any.
+func chainInputs(ctx context.Context) map[string]any {
+	inputs, _ := ctx.Value(chainInputsKey{}).(map[string]any)
+	return inputs
+}
+
+// pathSegment is one step of a reference path: a map key or a slice index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s pathSegment) String() string {
+	if s.isIndex {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return "." + s.key
+}
+
+// parsePath parses a reference path such as "steps.fetch.structured.items[0].id"
+// or "$.steps[1].result". A leading "$" or "$." is optional.
+// Keys are separated by "." and indices or quoted keys use brackets.
+func parsePath(expr string) ([]pathSegment, error) {
+	p := strings.TrimSpace(expr)
+	p = strings.TrimPrefix(p, "$")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var segs []pathSegment
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			if i == 0 || i == len(p)-1 || p[i+1] == '.' || p[i+1] == '[' {
+				return nil, fmt.Errorf("unexpected '.' at offset %d", i)
+			}
+			i++
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' at offset %d", i)
+			}
+			inner := p[i+1 : i+end]
+			if n, err := strconv.Atoi(inner); err == nil {
+				if n < 0 {
+					return nil, fmt.Errorf("negative index %d", n)
+				}
+				segs = append(segs, pathSegment{index: n, isIndex: true})
+			} else if unq, err := strconv.Unquote(inner); err == nil && len(inner) >= 2 {
+				segs = append(segs, pathSegment{key: unq})
+			} else {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+			i += end + 1
+		default:
+			if len(segs) > 0 && p[i-1] != '.' {
+				return nil, fmt.Errorf("expected '.' or '[' at offset %d", i)
+			}
+			j := i
+			for j < len(p) && p[j] != '.' && p[j] != '[' {
+				if p[j] == ']' || p[j] == ' ' {
+					return nil, fmt.Errorf("unexpected %q at offset %d", p[j], j)
+				}
+				j++
+			}
+			segs = append(segs, pathSegment{key: p[i:j]})
+			i = j
+		}
+	}
+	return segs, nil
+}
+
+// chainScope is the data a step can reference: earlier step results and the
+// value that would be injected as args["previous"].
+type chainScope struct {
+	// steps holds results visible to the current step, by chain position.
+	// Nil entries are steps that have not completed (or are not ancestors
+	// of a graph step).
+	steps []*StepResult
+
+	// previous is the value injected at args["previous"].
+	previous any
+
+	// inputs are the chain inputs attached with WithChainInputs.
+	inputs map[string]any
+
+	// item is the current element while a ForEach step iterates.
+	item any
+}
+
+// advance records a completed step in the scope and returns the chain's
+// final result so far. Skipped steps leave previous and final unchanged.
+func (s *chainScope) advance(sr StepResult, final RunResult) RunResult {
+	completed := sr
+	s.steps = append(s.steps, &completed)
+	if sr.Skipped {
+		return final
+	}
+	s.previous = sr.Result.Structured
+	return sr.Result
+}
+
+// lookup evaluates a parsed path against the scope.
+// Supported roots are "steps", "previous", "inputs", and "item".
+func (s *chainScope) lookup(segs []pathSegment) (any, error) {
+	root := segs[0]
+	switch {
+	case !root.isIndex && root.key == "previous":
+		return walkPath(s.previous, segs[1:])
+	case !root.isIndex && root.key == "inputs":
+		return walkPath(s.inputs, segs[1:])
+	case !root.isIndex && root.key == "item":
+		return walkPath(s.item, segs[1:])
+	case !root.isIndex && root.key == "steps":
+		if len(segs) < 2 {
+			return nil, fmt.Errorf("steps reference needs a step id or index")
+		}
+		step, err := s.step(segs[1])
+		if err != nil {
+			return nil, err
+		}
+		if len(segs) < 3 {
+			return nil, fmt.Errorf("steps reference needs a field (structured, result, toolId, id)")
+		}
+		field := segs[2]
+		var v any
+		switch {
+		case field.isIndex:
+			return nil, fmt.Errorf("unexpected index %s on step", field)
+		case field.key == "structured" || field.key == "result":
+			v = step.Result.Structured
+		case field.key == "toolId":
+			v = step.ToolID
+		case field.key == "id":
+			v = step.ID
+		default:
+			return nil, fmt.Errorf("unknown step field %q", field.key)
+		}
+		return walkPath(v, segs[3:])
+	default:
+		return nil, fmt.Errorf("unknown reference root %q", strings.TrimPrefix(root.String(), "."))
+	}
+}
+
+// step finds an earlier step by ID or position.
+func (s *chainScope) step(seg pathSegment) (*StepResult, error) {
+	if !seg.isIndex {
+		for _, sr := range s.steps {
+			if sr != nil && sr.ID == seg.key {
+				return sr, nil
+			}
+		}
+		n, err := strconv.Atoi(seg.key)
+		if err != nil {
+			return nil, fmt.Errorf("no completed step with id %q", seg.key)
+		}
+		seg = pathSegment{index: n, isIndex: true}
+	}
+	if seg.index >= len(s.steps) || s.steps[seg.index] == nil {
+		return nil, fmt.Errorf("step %d has not completed", seg.index)
+	}
+	return s.steps[seg.index], nil
+}
+
+// walkPath descends into v following segs.
+// Values that are not map[string]any or []any (for example structs returned by
+// local handlers) are normalized through JSON before descending.
+func walkPath(v any, segs []pathSegment) (any, error) {
+	for _, seg := range segs {
+		v = jsonNormalize(v)
+		switch cur := v.(type) {
+		case map[string]any:
+			key := seg.key
+			if seg.isIndex {
+				key = strconv.Itoa(seg.index)
+			}
+			next, ok := cur[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: key %q not found", errPathMissing, key)
+			}
+			v = next
+		case []any:
+			idx := seg.index
+			if !seg.isIndex {
+				n, err := strconv.Atoi(seg.key)
+				if err != nil {
+					return nil, fmt.Errorf("cannot access key %q on array", seg.key)
+				}
+				idx = n
+			}
+			if idx < 0 || idx >= len(cur) {
+				return nil, fmt.Errorf("%w: index %d out of range (len %d)", errPathMissing, idx, len(cur))
+			}
+			v = cur[idx]
+		case nil:
+			return nil, fmt.Errorf("%w: cannot access %s on null", errPathMissing, seg)
+		default:
+			return nil, fmt.Errorf("cannot access %s on %T", seg, cur)
+		}
+	}
+	return v, nil
+}
+
+// jsonNormalize converts composite Go values into their generic JSON form
+// (map[string]any / []any). Scalars and already-generic values are returned as-is.
+func jsonNormalize(v any) any {
+	switch v.(type) {
+	case nil, map[string]any, []any, string, bool, float64:
+		return v
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Pointer:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var out any
+		if err := json.Unmarshal(data, &out); err != nil {
+			return v
+		}
+		return out
+	}
+	return v
+}
+
+// refExpr reports whether s is a reference and returns its path expression.
+// A reference is either a whole-string template "{{ path }}" or a string
+// starting with "$.". Escaped references (see unescapeRef) are not references.
+func refExpr(s string) (string, bool) {
+	t := strings.TrimSpace(s)
+	if strings.HasPrefix(t, "{{") && strings.HasSuffix(t, "}}") && len(t) >= 4 {
+		return strings.TrimSpace(t[2 : len(t)-2]), true
+	}
+	if strings.HasPrefix(s, "$.") {
+		return s, true
+	}
+	return "", false
+}
+
+// refEscape marks a string that would otherwise be a reference as a literal.
+const refEscape = `\`
+
+// unescapeRef reports whether s is an escaped reference: a string that,
+// after one or more leading backslashes, would be a reference. Its literal
+// value, with one backslash removed, is returned, so `\$.50` is "$.50" and
+// `\\{{name}}` is `\{{name}}`.
+func unescapeRef(s string) (string, bool) {
+	rest := strings.TrimLeft(s, refEscape)
+	if len(rest) == len(s) {
+		return "", false
+	}
+	if _, ok := refExpr(rest); !ok {
+		return "", false
+	}
+	return s[len(refEscape):], true
+}
+
+// resolveRefs returns a copy of v with every reference replaced by the value
+// it points to. Nested maps and slices are copied; v itself is never modified.
+func resolveRefs(v any, scope *chainScope) (any, error) {
+	switch val := v.(type) {
+	case string:
+		if lit, ok := unescapeRef(val); ok {
+			return lit, nil
+		}
+		expr, ok := refExpr(val)
+		if !ok {
+			return val, nil
+		}
+		segs, err := parsePath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+		}
+		out, err := scope.lookup(segs)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+		}
+		return out, nil
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			resolved, err := resolveRefs(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			resolved, err := resolveRefs(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// checkRefs statically validates the references in v with checkPath.
+func checkRefs(v any, earlier map[string]bool, allowItem bool) error {
+	switch val := v.(type) {
+	case string:
+		expr, ok := refExpr(val)
+		if !ok {
+			return nil
+		}
+		segs, err := parsePath(expr)
+		if err != nil {
+			return fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+		}
+		if err := checkPath(segs, earlier, allowItem); err != nil {
+			return fmt.Errorf("%w: %q: %v", ErrInvalidReference, expr, err)
+		}
+	case map[string]any:
+		for _, item := range val {
+			if err := checkRefs(item, earlier, allowItem); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if err := checkRefs(item, earlier, allowItem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPath statically validates a parsed path: it must use a known root, and
+// any step it names must be one of the earlier steps.
+// earlier maps visible step IDs and indices (as strings) to true.
+// The "item" root is only valid inside ForEach steps (allowItem).
+func checkPath(segs []pathSegment, earlier map[string]bool, allowItem bool) error {
+	root := segs[0]
+	switch {
+	case !root.isIndex && (root.key == "previous" || root.key == "inputs"):
+		return nil
+	case !root.isIndex && root.key == "item" && allowItem:
+		return nil
+	case !root.isIndex && root.key == "steps":
+		if len(segs) < 2 {
+			return fmt.Errorf("steps reference needs a step id or index")
+		}
+		name := segs[1].key
+		if segs[1].isIndex {
+			name = strconv.Itoa(segs[1].index)
+		}
+		if !earlier[name] {
+			return fmt.Errorf("step %q is not an earlier step", name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown reference root %q", strings.TrimPrefix(root.String(), "."))
+	}
+}
+
+// checkStepRefs validates a step's references, When condition, ForEach
+// path, and projection against the steps visible to it.
+func checkStepRefs(step ChainStep, earlier map[string]bool) error {
+	if err := checkRefs(step.Args, earlier, step.ForEach != ""); err != nil {
+		return WrapError(step.ToolID, nil, "resolve_args", err)
+	}
+	if err := checkWhen(step.When, earlier); err != nil {
+		return WrapError(step.ToolID, nil, "when", err)
+	}
+	if err := checkForEach(step, earlier); err != nil {
+		return WrapError(step.ToolID, nil, "for_each", err)
+	}
+	return checkProject(step)
+}
+
+// checkChainRefs validates every step's references before a chain runs.
+func checkChainRefs(steps []ChainStep) error {
+	earlier := make(map[string]bool, len(steps)*2)
+	for i, step := range steps {
+		if err := checkStepRefs(step, earlier); err != nil {
+			return err
+		}
+		earlier[strconv.Itoa(i)] = true
+		if step.ID != "" {
+			earlier[step.ID] = true
+		}
+		if err := checkCompensation(step, earlier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
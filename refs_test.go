@@ -0,0 +1,250 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{
+			expr: "steps.fetch.structured.items[0].id",
+			want: []pathSegment{
+				{key: "steps"}, {key: "fetch"}, {key: "structured"}, {key: "items"},
+				{index: 0, isIndex: true}, {key: "id"},
+			},
+		},
+		{
+			expr: "$.steps[1].result",
+			want: []pathSegment{{key: "steps"}, {index: 1, isIndex: true}, {key: "result"}},
+		},
+		{
+			expr: `previous["a.b"]`,
+			want: []pathSegment{{key: "previous"}, {key: "a.b"}},
+		},
+		{expr: "", wantErr: true},
+		{expr: "steps..x", wantErr: true},
+		{expr: "steps[", wantErr: true},
+		{expr: "steps[x]", wantErr: true},
+		{expr: "steps[-1]", wantErr: true},
+		{expr: "steps[0]x", wantErr: true},
+		{expr: "steps.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parsePath(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePath(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePath(%q) = %#v, want %#v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkPath_NormalizesStructs(t *testing.T) {
+	type item struct {
+		ID string `json:"id"`
+	}
+	v := map[string]any{"items": []item{{ID: "first"}}}
+	segs, err := parsePath("items[0].id")
+	if err != nil {
+		t.Fatalf("parsePath() error = %v", err)
+	}
+	got, err := walkPath(v, segs)
+	if err != nil {
+		t.Fatalf("walkPath() error = %v", err)
+	}
+	if got != "first" {
+		t.Errorf("walkPath() = %v, want first", got)
+	}
+}
+
+func TestRunChain_References(t *testing.T) {
+	var received map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fetch": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{"items": []any{map[string]any{"id": "item-1"}}}, nil
+		},
+		"count": func(_ context.Context, _ map[string]any) (any, error) {
+			return 3, nil
+		},
+		"use": func(_ context.Context, args map[string]any) (any, error) {
+			received = args
+			return "ok", nil
+		},
+	})
+
+	args := map[string]any{
+		"id":     "{{steps.fetch.structured.items[0].id}}",
+		"count":  "$.steps[1].result",
+		"nested": map[string]any{"tool": "{{ steps.0.toolId }}"},
+		"plain":  "literal",
+	}
+	steps := []ChainStep{
+		{ID: "fetch", ToolID: "fetch"},
+		{ToolID: "count"},
+		{ToolID: "use", Args: args},
+	}
+
+	if _, _, err := runner.RunChain(context.Background(), steps); err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if received["id"] != "item-1" {
+		t.Errorf("id = %v, want item-1", received["id"])
+	}
+	if received["count"] != 3 {
+		t.Errorf("count = %v, want 3", received["count"])
+	}
+	if nested, _ := received["nested"].(map[string]any); nested["tool"] != "fetch" {
+		t.Errorf("nested = %v, want map[tool:fetch]", received["nested"])
+	}
+	if received["plain"] != "literal" {
+		t.Errorf("plain = %v, want literal", received["plain"])
+	}
+	if args["id"] != "{{steps.fetch.structured.items[0].id}}" {
+		t.Error("RunChain() must not modify step args")
+	}
+}
+
+func TestRunChain_Reference_BadPathFailsBeforeDispatch(t *testing.T) {
+	called := false
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"fetch": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{"items": []any{}}, nil
+		},
+		"use": func(_ context.Context, _ map[string]any) (any, error) {
+			called = true
+			return "ok", nil
+		},
+	})
+
+	steps := []ChainStep{
+		{ID: "fetch", ToolID: "fetch"},
+		{ToolID: "use", Args: map[string]any{"id": "{{steps.fetch.structured.items[0].id}}"}},
+	}
+
+	_, results, err := runner.RunChain(context.Background(), steps)
+	if !errors.Is(err, ErrInvalidReference) {
+		t.Fatalf("RunChain() error = %v, want ErrInvalidReference", err)
+	}
+	if called {
+		t.Error("step with bad reference should not be dispatched")
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "resolve_args" {
+		t.Errorf("error = %v, want ToolError with op resolve_args", err)
+	}
+	if len(results) != 2 || results[1].Err == nil {
+		t.Errorf("results = %v, want failing second step", results)
+	}
+}
+
+func TestRunChain_Reference_StaticChecks(t *testing.T) {
+	called := false
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"t": func(_ context.Context, _ map[string]any) (any, error) {
+			called = true
+			return "ok", nil
+		},
+	})
+
+	tests := []struct {
+		name string
+		args map[string]any
+	}{
+		{name: "forward reference", args: map[string]any{"x": "{{steps.later.structured}}"}},
+		{name: "self index", args: map[string]any{"x": "$.steps[0].result"}},
+		{name: "unknown root", args: map[string]any{"x": "{{nope.value}}"}},
+		{name: "syntax", args: map[string]any{"x": []any{"{{steps[}}"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := []ChainStep{
+				{ToolID: "t", Args: tt.args},
+				{ID: "later", ToolID: "t"},
+			}
+			_, results, err := runner.RunChain(context.Background(), steps)
+			if !errors.Is(err, ErrInvalidReference) {
+				t.Fatalf("RunChain() error = %v, want ErrInvalidReference", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("len(results) = %d, want 0", len(results))
+			}
+			if called {
+				t.Error("no step should run when references are invalid")
+			}
+		})
+	}
+}
+
+func TestRunChain_Reference_EscapedLiterals(t *testing.T) {
+	var received map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"use": func(_ context.Context, args map[string]any) (any, error) {
+			received = args
+			return "ok", nil
+		},
+	})
+
+	args := map[string]any{
+		"price":    `\$.50`,
+		"template": `\{{name}}`,
+		"nested":   []any{map[string]any{"x": `\\$.x`}},
+		"path":     `\server\share`,
+	}
+	if _, _, err := runner.RunChain(context.Background(), []ChainStep{{ToolID: "use", Args: args}}); err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	want := map[string]any{
+		"price":    "$.50",
+		"template": "{{name}}",
+		"nested":   []any{map[string]any{"x": `\$.x`}},
+		"path":     `\server\share`,
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("args = %v, want %v", received, want)
+	}
+	if err := runner.CheckChain(context.Background(), []ChainStep{{ToolID: "use", Args: args}}); err != nil {
+		t.Errorf("CheckChain() error = %v, want escaped literals accepted", err)
+	}
+}
+
+func TestRunGraph_References(t *testing.T) {
+	var received map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"src": func(_ context.Context, _ map[string]any) (any, error) {
+			return map[string]any{"v": "from-src"}, nil
+		},
+		"use": func(_ context.Context, args map[string]any) (any, error) {
+			received = args
+			return "ok", nil
+		},
+	})
+
+	steps := []GraphStep{
+		{ChainStep: ChainStep{ID: "src", ToolID: "src"}},
+		{ChainStep: ChainStep{ID: "use", ToolID: "use", Args: map[string]any{"v": "{{steps.src.structured.v}}"}}, DependsOn: []string{"src"}},
+	}
+	if _, err := runner.RunGraph(context.Background(), steps, 0); err != nil {
+		t.Fatalf("RunGraph() error = %v", err)
+	}
+	if received["v"] != "from-src" {
+		t.Errorf("v = %v, want from-src", received["v"])
+	}
+
+	// References to non-ancestors are rejected up front.
+	steps[1].DependsOn = nil
+	if _, err := runner.RunGraph(context.Background(), steps, 0); !errors.Is(err, ErrInvalidReference) {
+		t.Errorf("RunGraph() error = %v, want ErrInvalidReference", err)
+	}
+}
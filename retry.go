@@ -0,0 +1,145 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how Run retries a failed attempt. The zero value
+// makes a single attempt.
+//
+// An attempt covers dispatch, normalization, and output validation; tool
+// resolution and input validation run once, before the first attempt.
+// Delays grow exponentially from InitialBackoff by Multiplier, are capped at
+// MaxBackoff, and are randomized by Jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// InitialBackoff is the delay before the second attempt.
+	// Defaults to 100ms.
+	InitialBackoff time.Duration `json:"initialBackoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty"`
+
+	// Multiplier scales the delay after each attempt. Defaults to 2.
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// Jitter randomizes each delay by up to plus or minus this fraction of
+	// it, for example 0.2 for ±20%. Values are clamped to [0, 1].
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// Retryable reports whether a failed attempt should be retried.
+	// Nil uses DefaultRetryable.
+	Retryable func(err error) bool `json:"-"`
+}
+
+// DefaultRetryable is the retry predicate used when RetryPolicy.Retryable is
+// nil. It retries execution failures (ToolError.Op "execute" or "stream") and
+// calls ended by the runner's execution timeout (ErrCallTimeout), and never
+// retries validation errors, resolution errors, or context errors.
+func DefaultRetryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrCallTimeout):
+		return true
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrOutputValidation),
+		errors.Is(err, ErrInvalidToolID), errors.Is(err, ErrToolNotFound),
+		errors.Is(err, ErrNoBackends),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		return false
+	}
+	return toolErr.Op == "execute" || toolErr.Op == "stream"
+}
+
+// retryPolicyKey is the context key for a per-call retry policy.
+type retryPolicyKey struct{}
+
+// WithCallRetryPolicy returns a context whose Run calls use policy instead of
+// Config.RetryPolicy. Pass a zero RetryPolicy to disable retries for a call.
+func WithCallRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicy returns the retry policy that applies to a call.
+func (r *DefaultRunner) retryPolicy(ctx context.Context) RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return r.cfg.RetryPolicy
+}
+
+// retryable reports whether err may be retried under the policy.
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// backoff returns the delay after the given (1-based) failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	if delay <= 0 {
+		delay = float64(100 * time.Millisecond)
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	for i := 1; i < attempt; i++ {
+		delay *= mult
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		delay *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// retryFunc is notified before each retry with the failed attempt's number
+// and error and the delay before the next attempt.
+type retryFunc func(attempt int, err error, delay time.Duration)
+
+// withRetries calls attempt until it succeeds, the policy gives up, or ctx
+// is done, and returns the last result with the number of attempts made.
+// When the last attempt fails, the number is recorded in its ToolError.
+func withRetries(ctx context.Context, policy RetryPolicy, onRetry retryFunc, attempt func() (RunResult, error)) (RunResult, error) {
+	for n := 1; ; n++ {
+		result, err := attempt()
+		if err == nil {
+			result.Attempts = n
+			return result, nil
+		}
+		if n >= policy.MaxAttempts || ctx.Err() != nil || !policy.retryable(err) {
+			var toolErr *ToolError
+			if errors.As(err, &toolErr) {
+				toolErr.Attempts = n
+			}
+			return RunResult{}, err
+		}
+		delay := policy.backoff(n)
+		if onRetry != nil {
+			onRetry(n, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return RunResult{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
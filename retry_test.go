@@ -0,0 +1,183 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyHandler fails the first failures calls and then returns "ok".
+func flakyHandler(calls *atomic.Int32, failures int32) LocalHandler {
+	return func(_ context.Context, _ map[string]any) (any, error) {
+		if calls.Add(1) <= failures {
+			return nil, errTest
+		}
+		return "ok", nil
+	}
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+func TestRun_Retry(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 2)})
+	runner.cfg.RetryPolicy = fastRetry
+
+	result, err := runner.Run(context.Background(), "flaky", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Structured != "ok" || result.Attempts != 3 {
+		t.Errorf("result = %v after %d attempts, want ok after 3", result.Structured, result.Attempts)
+	}
+}
+
+func TestRun_RetryExhausted(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 5)})
+	runner.cfg.RetryPolicy = fastRetry
+
+	_, err := runner.Run(context.Background(), "flaky", nil)
+	if !errors.Is(err, ErrExecution) {
+		t.Errorf("Run() error = %v, want ErrExecution", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Attempts != 3 {
+		t.Errorf("error = %v, want ToolError with 3 attempts", err)
+	}
+}
+
+func TestRun_NoRetryByDefault(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 1)})
+
+	if _, err := runner.Run(context.Background(), "flaky", nil); err == nil {
+		t.Fatal("Run() error = nil, want error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestRun_RetrySkipsOutputValidation(t *testing.T) {
+	var calls atomic.Int32
+	idx := newMockIndex()
+	mustRegisterTool(t, idx, testToolWithOutputSchema("bad"), testLocalBackend("bad"))
+	localReg := newMockLocalRegistry()
+	localReg.Register("bad", func(_ context.Context, _ map[string]any) (any, error) {
+		calls.Add(1)
+		return "not an object", nil
+	})
+	runner := NewRunner(WithIndex(idx), WithLocalRegistry(localReg), WithRetryPolicy(fastRetry))
+
+	_, err := runner.Run(context.Background(), "bad", nil)
+	if !errors.Is(err, ErrOutputValidation) {
+		t.Fatalf("Run() error = %v, want ErrOutputValidation", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestRun_CallRetryPolicy(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 1)})
+	runner.cfg.RetryPolicy = fastRetry
+
+	ctx := WithCallRetryPolicy(context.Background(), RetryPolicy{})
+	if _, err := runner.Run(ctx, "flaky", nil); err == nil {
+		t.Fatal("Run() error = nil, want error with retries disabled for the call")
+	}
+
+	calls.Store(0)
+	policy := fastRetry
+	policy.Retryable = func(err error) bool { return false }
+	ctx = WithCallRetryPolicy(context.Background(), policy)
+	if _, err := runner.Run(ctx, "flaky", nil); err == nil {
+		t.Fatal("Run() error = nil, want error with a predicate that never retries")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestRun_RetryCanceled(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 5)})
+	runner.cfg.RetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := runner.Run(ctx, "flaky", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunWithProgress_RetryEvents(t *testing.T) {
+	var calls atomic.Int32
+	runner := newLocalTestRunner(t, map[string]LocalHandler{"flaky": flakyHandler(&calls, 2)})
+	runner.cfg.RetryPolicy = fastRetry
+
+	var events []ProgressEvent
+	_, err := runner.RunWithProgress(context.Background(), "flaky", nil, func(ev ProgressEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("RunWithProgress() error = %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events %+v, want 4", len(events), events)
+	}
+	for i, ev := range events[1:3] {
+		if !strings.HasPrefix(ev.Message, "retry ") {
+			t.Errorf("events[%d].Message = %q, want a retry event", i+1, ev.Message)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	want := []time.Duration{10, 20, 30, 30}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.backoff(1); got < 5*time.Millisecond || got > 15*time.Millisecond {
+			t.Fatalf("backoff(1) with jitter = %v, want within [5ms, 15ms]", got)
+		}
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"execute", WrapError("t", nil, "execute", ErrExecution), true},
+		{"stream", WrapError("t", nil, "stream", errTest), true},
+		{"validation", WrapError("t", nil, "validate_input", ErrValidation), false},
+		{"output validation", WrapError("t", nil, "validate_output", ErrOutputValidation), false},
+		{"not found", WrapError("t", nil, "resolve", ErrToolNotFound), false},
+		{"canceled", WrapError("t", nil, "execute", context.Canceled), false},
+		{"plain", errTest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultRetryable(tt.err); got != tt.want {
+				t.Errorf("DefaultRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
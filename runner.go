@@ -28,7 +28,8 @@ type Runner interface {
 
 	// RunChain executes a sequence of tool steps.
 	// Returns the final result and a slice of step results.
-	// Stops on the first error (v1 policy).
+	// Stops on the first error unless the step's ErrorPolicy continues or
+	// falls back (see Config.ChainErrorPolicy and ChainStep.OnError).
 	// If UsePrevious is true for a step, the previous step's Structured result
 	// is injected at args["previous"], overwriting any existing value,
 	// even when the previous result is nil.
@@ -54,3 +55,50 @@ type ProgressRunner interface {
 	// RunChainWithProgress executes a chain and emits progress updates.
 	RunChainWithProgress(ctx context.Context, steps []ChainStep, onProgress ProgressCallback) (RunResult, []StepResult, error)
 }
+
+// GraphRunner is an optional interface for executing steps as a dependency
+// graph rather than a strict sequence.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines and return ctx.Err() when canceled.
+// - Ordering: a step starts only after all of its DependsOn steps completed,
+//   either successfully or through a continue/fallback error policy.
+// - Results: one StepResult is returned per step, in the order of the input slice.
+// - Errors: malformed graphs return ErrInvalidChain before any step executes;
+//   steps skipped because of an upstream failure record ErrDependencyFailed.
+type GraphRunner interface {
+	// RunGraph executes steps concurrently where dependencies allow, running at
+	// most parallelism steps at once. A parallelism of zero or less means no limit.
+	// Returns the first step error encountered, if any.
+	RunGraph(ctx context.Context, steps []GraphStep, parallelism int) ([]StepResult, error)
+}
+
+// ResumableRunner is an optional interface for resuming checkpointed chains.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines and return ctx.Err() when canceled.
+// - Results: recorded steps are returned first, followed by the steps run now.
+// - Errors: unknown run IDs return ErrCheckpointNotFound; step failures follow
+//   RunChain semantics.
+type ResumableRunner interface {
+	// ResumeChain continues the chain run identified by runID from its first
+	// incomplete step.
+	ResumeChain(ctx context.Context, runID string) (RunResult, []StepResult, error)
+}
+
+// ChainStreamRunner is an optional interface for streaming chain execution.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines; the channel is closed when
+//   the chain ends or ctx is canceled.
+// - Events: step events carry StepIndex and ToolID; the stream ends with
+//   exactly one StreamEventDone or StreamEventError event unless canceled.
+// - Errors: malformed chains are returned synchronously with ErrInvalidChain
+//   or ErrInvalidReference; step failures follow RunChain semantics.
+type ChainStreamRunner interface {
+	// RunChainStream executes a chain and streams step and tool events.
+	RunChainStream(ctx context.Context, steps []ChainStep) (<-chan StreamEvent, error)
+}
@@ -0,0 +1,365 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// secretRefKey is the key of a secret reference object: {"$secret": "name"}.
+const secretRefKey = "$secret"
+
+// Redacted replaces resolved secret values in errors, results, and events.
+const Redacted = "[REDACTED]"
+
+// SecretProvider resolves the secret references in tool args, such as
+// {"$secret": "github/token"}, to their values.
+//
+// Contract:
+// - Concurrency: implementations must be safe for concurrent use.
+// - Context: must honor cancellation/deadlines and return ctx.Err() when canceled.
+// - Errors: return an error matching ErrSecretNotFound for unknown names.
+// - Ownership: returned values are never logged or returned by the runner.
+type SecretProvider interface {
+	// Secret returns the value of the named secret.
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// EnvSecretProvider resolves secrets from environment variables. A name is
+// mapped to a variable by upper-casing it and replacing characters other
+// than letters and digits with "_", after Prefix: with Prefix "TOOLRUN_",
+// "github/token" is read from TOOLRUN_GITHUB_TOKEN.
+type EnvSecretProvider struct {
+	// Prefix is prepended to every variable name.
+	Prefix string
+}
+
+// Secret implements SecretProvider.
+func (p EnvSecretProvider) Secret(_ context.Context, name string) (string, error) {
+	key := p.Prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, key)
+	}
+	return value, nil
+}
+
+// FileSecretProvider resolves secrets from files under Dir, as mounted by
+// Kubernetes or Docker secrets: "github/token" is read from
+// Dir/github/token, without trailing newlines. Names that would escape Dir
+// are rejected.
+type FileSecretProvider struct {
+	// Dir is the directory holding the secret files.
+	Dir string
+}
+
+// Secret implements SecretProvider.
+func (p FileSecretProvider) Secret(_ context.Context, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: invalid secret name", ErrSecretNotFound)
+	}
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: no secret file", ErrSecretNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// secretCache is a SecretProvider that looks up each name at most once, so
+// that the validation, retries, and failover of one call share its secrets.
+// It is safe for concurrent use.
+type secretCache struct {
+	p SecretProvider
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// newSecretCache returns a cache of the configured provider's secrets for
+// one call.
+func (r *DefaultRunner) newSecretCache() *secretCache {
+	return &secretCache{p: r.cfg.Secrets}
+}
+
+// Secret implements SecretProvider. Failed lookups are not cached.
+func (c *secretCache) Secret(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.values[name]; ok {
+		return value, nil
+	}
+	value, err := c.p.Secret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if c.values == nil {
+		c.values = make(map[string]string)
+	}
+	c.values[name] = value
+	return value, nil
+}
+
+// resolveSecrets returns args with every secret reference replaced by its
+// value, and a redactor for the values. Lookups go through secrets, the
+// call's cache, when it is non-nil. args is never modified; changed maps and
+// slices are copied. Without a provider, references are left as they are.
+func (r *DefaultRunner) resolveSecrets(ctx context.Context, call Call, secrets *secretCache) (map[string]any, *redactor, error) {
+	if r.cfg.Secrets == nil {
+		return call.Args, nil, nil
+	}
+	var p SecretProvider = r.cfg.Secrets
+	if secrets != nil {
+		p = secrets
+	}
+	red := &redactor{}
+	out, changed, err := red.resolve(ctx, p, call.Args)
+	if err != nil {
+		return nil, nil, WrapError(call.ToolID, &call.Backend, "secret", err)
+	}
+	if !changed {
+		return call.Args, nil, nil
+	}
+	// Replace longer values first, so that a secret containing another is
+	// not partially revealed.
+	slices.SortFunc(red.values, func(a, b string) int { return len(b) - len(a) })
+	return out.(map[string]any), red, nil
+}
+
+// secretRef returns the name of a secret reference.
+func secretRef(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	name, ok := m[secretRefKey].(string)
+	return name, ok
+}
+
+// redactor replaces resolved secret values with Redacted. A nil redactor
+// redacts nothing.
+type redactor struct {
+	values []string
+}
+
+// resolve replaces the secret references in v, recording their values and
+// reporting whether the returned value differs from v.
+func (red *redactor) resolve(ctx context.Context, p SecretProvider, v any) (any, bool, error) {
+	if name, ok := secretRef(v); ok {
+		value, err := p.Secret(ctx, name)
+		if err != nil {
+			return nil, false, fmt.Errorf("secret %q: %w", name, err)
+		}
+		if value != "" && !slices.Contains(red.values, value) {
+			red.values = append(red.values, value)
+		}
+		return value, true, nil
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		var out map[string]any
+		for k, item := range val {
+			next, changed, err := red.resolve(ctx, p, item)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = maps.Clone(val)
+			}
+			out[k] = next
+		}
+		if out != nil {
+			return out, true, nil
+		}
+	case []any:
+		var out []any
+		for i, item := range val {
+			next, changed, err := red.resolve(ctx, p, item)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(val)
+			}
+			out[i] = next
+		}
+		if out != nil {
+			return out, true, nil
+		}
+	}
+	return v, false, nil
+}
+
+// contains reports whether s contains a secret value.
+func (red *redactor) contains(s string) bool {
+	if red == nil {
+		return false
+	}
+	for _, v := range red.values {
+		if strings.Contains(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// string redacts s.
+func (red *redactor) string(s string) string {
+	if !red.contains(s) {
+		return s
+	}
+	for _, v := range red.values {
+		s = strings.ReplaceAll(s, v, Redacted)
+	}
+	return s
+}
+
+// value redacts the strings in a JSON-like value. Other values are redacted
+// through their JSON encoding when it contains a secret.
+func (red *redactor) value(v any) any {
+	if red == nil {
+		return v
+	}
+	switch val := v.(type) {
+	case nil, bool, float64, int, int64:
+		return v
+	case string:
+		return red.string(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[red.string(k)] = red.value(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = red.value(item)
+		}
+		return out
+	case *mcp.CallToolResult:
+		return red.mcpResult(val)
+	case StepResult:
+		return red.step(val)
+	}
+	data, err := json.Marshal(v)
+	if err != nil || !red.contains(string(data)) {
+		return v
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return v
+	}
+	return red.value(generic)
+}
+
+// err redacts an error's message, keeping it matchable with errors.Is and
+// errors.As.
+func (red *redactor) err(err error) error {
+	if err == nil || !red.contains(err.Error()) {
+		return err
+	}
+	return &redactedError{err: err, red: red}
+}
+
+// redactedError is an error whose message has secret values redacted.
+type redactedError struct {
+	err error
+	red *redactor
+}
+
+func (e *redactedError) Error() string { return e.red.string(e.err.Error()) }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// dispatchResult redacts a dispatch result in place.
+func (red *redactor) dispatchResult(dr *dispatchResult) {
+	if red == nil || dr == nil {
+		return
+	}
+	dr.structured = red.value(dr.structured)
+	dr.mcpResult = red.mcpResult(dr.mcpResult)
+	dr.steps = red.steps(dr.steps)
+}
+
+// mcpResult returns a redacted copy of an MCP result.
+func (red *redactor) mcpResult(res *mcp.CallToolResult) *mcp.CallToolResult {
+	if res == nil {
+		return nil
+	}
+	out := *res
+	out.Content = slices.Clone(res.Content)
+	for i, c := range out.Content {
+		if text, ok := c.(*mcp.TextContent); ok && red.contains(text.Text) {
+			redacted := *text
+			redacted.Text = red.string(text.Text)
+			out.Content[i] = &redacted
+		}
+	}
+	out.StructuredContent = red.value(res.StructuredContent)
+	return &out
+}
+
+// steps returns redacted copies of step results.
+func (red *redactor) steps(steps []StepResult) []StepResult {
+	if steps == nil {
+		return nil
+	}
+	out := make([]StepResult, len(steps))
+	for i, sr := range steps {
+		out[i] = red.step(sr)
+	}
+	return out
+}
+
+func (red *redactor) step(sr StepResult) StepResult {
+	sr.Result.Structured = red.value(sr.Result.Structured)
+	sr.Result.MCPResult = red.mcpResult(sr.Result.MCPResult)
+	sr.Result.Steps = red.steps(sr.Result.Steps)
+	sr.Err = red.err(sr.Err)
+	sr.Iterations = red.steps(sr.Iterations)
+	if sr.Fallback != nil {
+		fb := red.step(*sr.Fallback)
+		sr.Fallback = &fb
+	}
+	if sr.Compensation != nil {
+		comp := red.step(*sr.Compensation)
+		sr.Compensation = &comp
+	}
+	return sr
+}
+
+// event redacts a stream event.
+func (red *redactor) event(ev StreamEvent) StreamEvent {
+	if red == nil {
+		return ev
+	}
+	ev.Data = red.value(ev.Data)
+	ev.Err = red.err(ev.Err)
+	return ev
+}
@@ -0,0 +1,303 @@
+package toolrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// mapSecrets is a SecretProvider backed by a map.
+type mapSecrets map[string]string
+
+func (m mapSecrets) Secret(_ context.Context, name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return v, nil
+}
+
+// countingSecrets is a SecretProvider that counts its lookups.
+type countingSecrets struct {
+	SecretProvider
+	lookups atomic.Int32
+}
+
+func (c *countingSecrets) Secret(ctx context.Context, name string) (string, error) {
+	c.lookups.Add(1)
+	return c.SecretProvider.Secret(ctx, name)
+}
+
+const testSecret = "s3cr3t-value"
+
+func secretRunner(t *testing.T, handlers map[string]LocalHandler) *DefaultRunner {
+	t.Helper()
+	runner := newLocalTestRunner(t, handlers)
+	runner.cfg.Secrets = mapSecrets{"github/token": testSecret}
+	return runner
+}
+
+func echoHandler(_ context.Context, args map[string]any) (any, error) {
+	return map[string]any{"echo": fmt.Sprint(args["token"])}, nil
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("TOOLRUN_GITHUB_TOKEN", "tok")
+	p := EnvSecretProvider{Prefix: "TOOLRUN_"}
+
+	got, err := p.Secret(context.Background(), "github/token")
+	if err != nil || got != "tok" {
+		t.Errorf("Secret() = %q, %v; want %q", got, err, "tok")
+	}
+	if _, err := p.Secret(context.Background(), "github/missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Secret(missing) error = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "github"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "github", "token"), []byte("tok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := FileSecretProvider{Dir: dir}
+
+	got, err := p.Secret(context.Background(), "github/token")
+	if err != nil || got != "tok" {
+		t.Errorf("Secret() = %q, %v; want %q", got, err, "tok")
+	}
+	for _, name := range []string{"github/missing", "../token", "/etc/passwd"} {
+		if _, err := p.Secret(context.Background(), name); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("Secret(%q) error = %v, want ErrSecretNotFound", name, err)
+		}
+	}
+}
+
+func TestSecrets_ResolvedBeforeDispatch(t *testing.T) {
+	var seen map[string]any
+	runner := secretRunner(t, map[string]LocalHandler{
+		"tool": func(_ context.Context, args map[string]any) (any, error) {
+			seen = args
+			return "ok", nil
+		},
+	})
+	var hookArgs map[string]any
+	runner.cfg.Hooks.BeforeDispatch = func(_ context.Context, call Call) (map[string]any, error) {
+		hookArgs = call.Args
+		return call.Args, nil
+	}
+
+	ref := map[string]any{"$secret": "github/token"}
+	args := map[string]any{
+		"token":   ref,
+		"headers": []any{map[string]any{"auth": ref}},
+		"q":       "x",
+	}
+	if _, err := runner.Run(context.Background(), "tool", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]any{
+		"token":   testSecret,
+		"headers": []any{map[string]any{"auth": testSecret}},
+		"q":       "x",
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("handler args = %v, want %v", seen, want)
+	}
+	if !reflect.DeepEqual(hookArgs["token"], ref) {
+		t.Errorf("BeforeDispatch args[token] = %v, want the reference", hookArgs["token"])
+	}
+	if !reflect.DeepEqual(args["token"], ref) || !reflect.DeepEqual(args["headers"], []any{map[string]any{"auth": ref}}) {
+		t.Errorf("caller args modified: %v", args)
+	}
+}
+
+func TestSecrets_RedactsResultsAndErrors(t *testing.T) {
+	runner := secretRunner(t, map[string]LocalHandler{
+		"echo": echoHandler,
+		"fail": func(_ context.Context, args map[string]any) (any, error) {
+			return nil, fmt.Errorf("auth failed for %v", args["token"])
+		},
+	})
+	args := map[string]any{"token": map[string]any{"$secret": "github/token"}}
+
+	result, err := runner.Run(context.Background(), "echo", args)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := map[string]any{"echo": Redacted}; !reflect.DeepEqual(result.Structured, want) {
+		t.Errorf("Structured = %v, want %v", result.Structured, want)
+	}
+
+	_, err = runner.Run(context.Background(), "fail", args)
+	if !errors.Is(err, ErrExecution) {
+		t.Fatalf("Run() error = %v, want ErrExecution", err)
+	}
+	if msg := err.Error(); strings.Contains(msg, testSecret) || !strings.Contains(msg, Redacted) {
+		t.Errorf("error = %q, want the secret redacted", msg)
+	}
+}
+
+func TestSecrets_RedactsStepResults(t *testing.T) {
+	runner := secretRunner(t, map[string]LocalHandler{"echo": echoHandler})
+	steps := []ChainStep{{ToolID: "echo", Args: map[string]any{"token": map[string]any{"$secret": "github/token"}}}}
+
+	final, results, err := runner.RunChain(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	want := map[string]any{"echo": Redacted}
+	if !reflect.DeepEqual(final.Structured, want) {
+		t.Errorf("final.Structured = %v, want %v", final.Structured, want)
+	}
+	if !reflect.DeepEqual(results[0].Result.Structured, want) {
+		t.Errorf("step Structured = %v, want %v", results[0].Result.Structured, want)
+	}
+}
+
+func TestSecrets_RedactsStreamEvents(t *testing.T) {
+	newStream := func() chan StreamEvent {
+		stream := make(chan StreamEvent, 2)
+		stream <- StreamEvent{Kind: StreamEventChunk, Data: map[string]any{"text": "token=" + testSecret}}
+		stream <- StreamEvent{Kind: StreamEventDone, Data: testSecret}
+		close(stream)
+		return stream
+	}
+	args := map[string]any{"token": map[string]any{"$secret": "github/token"}}
+
+	t.Run("RunStream", func(t *testing.T) {
+		runner := newStreamChainRunner(t, newStream())
+		runner.cfg.Secrets = mapSecrets{"github/token": testSecret}
+		ch, err := runner.RunStream(context.Background(), "gen", args)
+		if err != nil {
+			t.Fatalf("RunStream() error = %v", err)
+		}
+		for _, ev := range collectEvents(ch) {
+			if s := fmt.Sprint(ev.Data); strings.Contains(s, testSecret) {
+				t.Errorf("%s event data = %q, want the secret redacted", ev.Kind, s)
+			}
+		}
+	})
+
+	t.Run("RunChainStream", func(t *testing.T) {
+		runner := newStreamChainRunner(t, newStream())
+		runner.cfg.Secrets = mapSecrets{"github/token": testSecret}
+		ch, err := runner.RunChainStream(context.Background(), []ChainStep{{ToolID: "gen", Args: args}})
+		if err != nil {
+			t.Fatalf("RunChainStream() error = %v", err)
+		}
+		events := collectEvents(ch)
+		if len(events) == 0 {
+			t.Fatal("no events")
+		}
+		for _, ev := range events {
+			if s := fmt.Sprintf("%+v", ev.Data); strings.Contains(s, testSecret) {
+				t.Errorf("%s event data = %q, want the secret redacted", ev.Kind, s)
+			}
+		}
+	})
+}
+
+func TestSecrets_MissingSecret(t *testing.T) {
+	called := false
+	runner := secretRunner(t, map[string]LocalHandler{
+		"tool": func(_ context.Context, _ map[string]any) (any, error) {
+			called = true
+			return "ok", nil
+		},
+	})
+
+	_, err := runner.Run(context.Background(), "tool", map[string]any{"token": map[string]any{"$secret": "missing"}})
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Run() error = %v, want ErrSecretNotFound", err)
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "secret" {
+		t.Errorf("error = %v, want a ToolError with Op %q", err, "secret")
+	}
+	if called {
+		t.Error("handler called despite a missing secret")
+	}
+}
+
+func TestSecrets_NoProvider(t *testing.T) {
+	var seen map[string]any
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"tool": func(_ context.Context, args map[string]any) (any, error) {
+			seen = args
+			return "ok", nil
+		},
+	})
+	ref := map[string]any{"$secret": "github/token"}
+
+	if _, err := runner.Run(context.Background(), "tool", map[string]any{"token": ref}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !reflect.DeepEqual(seen["token"], ref) {
+		t.Errorf("handler args[token] = %v, want the reference", seen["token"])
+	}
+}
+
+func TestSecrets_ValidatesResolvedValue(t *testing.T) {
+	runner := secretRunner(t, map[string]LocalHandler{"tool": echoHandler})
+	tool, _, err := runner.cfg.Index.GetTool("tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.InputSchema = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"token": map[string]any{"type": "string"}},
+	}
+	mustRegisterTool(t, runner.cfg.Index.(*mockIndex), tool, testLocalBackend("tool"))
+	runner.cfg.ValidateInput = true
+
+	if _, err := runner.Run(context.Background(), "tool", map[string]any{"token": map[string]any{"$secret": "github/token"}}); err != nil {
+		t.Errorf("Run() error = %v, want the resolved secret to validate", err)
+	}
+	if _, err := runner.Run(context.Background(), "tool", map[string]any{"token": 5}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Run() error = %v, want ErrValidation", err)
+	}
+}
+
+func TestSecrets_ResolvedOncePerCall(t *testing.T) {
+	var calls atomic.Int32
+	var seen []any
+	flaky := flakyHandler(&calls, 2)
+	runner := secretRunner(t, map[string]LocalHandler{
+		"tool": func(ctx context.Context, args map[string]any) (any, error) {
+			seen = append(seen, args["token"])
+			return flaky(ctx, args)
+		},
+	})
+	secrets := &countingSecrets{SecretProvider: runner.cfg.Secrets}
+	runner.cfg.Secrets = secrets
+	runner.cfg.ValidateInput = true
+	runner.cfg.RetryPolicy = fastRetry
+
+	args := map[string]any{"token": map[string]any{"$secret": "github/token"}}
+	if _, err := runner.Run(context.Background(), "tool", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []any{testSecret, testSecret, testSecret}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("handler tokens = %v, want %v", seen, want)
+	}
+	if n := secrets.lookups.Load(); n != 1 {
+		t.Errorf("lookups = %d, want 1 across validation and retries", n)
+	}
+
+	if _, err := runner.Run(context.Background(), "tool", args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if n := secrets.lookups.Load(); n != 2 {
+		t.Errorf("lookups = %d, want 2 after a second call", n)
+	}
+}
@@ -391,6 +391,23 @@ func testMCPResultJSON(v any) *mcp.CallToolResult {
 	}
 }
 
+// newLocalTestRunner creates a runner with one local tool per name, each backed
+// by the handler of the same name. Validation is disabled.
+func newLocalTestRunner(t *testing.T, handlers map[string]LocalHandler) *DefaultRunner {
+	t.Helper()
+	idx := newMockIndex()
+	localReg := newMockLocalRegistry()
+	for name, h := range handlers {
+		mustRegisterTool(t, idx, testTool(name), testLocalBackend(name))
+		localReg.Register(name, h)
+	}
+	return NewRunner(
+		WithIndex(idx),
+		WithLocalRegistry(localReg),
+		WithValidation(false, false),
+	)
+}
+
 // -----------------------------------------------------------------------------
 // Common Test Errors
 // -----------------------------------------------------------------------------
@@ -0,0 +1,177 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// Duration is a time.Duration that encodes to JSON as a Go duration string
+// such as "30s" or "1m30s", so that chain documents stay readable.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts duration strings only.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// TimeoutConfig sets default execution timeouts for backend calls, so that
+// callers need not bound every Run with their own deadline. Timeouts apply to
+// each non-streaming dispatch, including every retry, failover, and hedged
+// call; streams are not bounded. Zero fields impose no timeout.
+type TimeoutConfig struct {
+	// Local, Provider, and MCP are the defaults for calls to each backend
+	// kind.
+	Local    time.Duration `json:"local,omitempty"`
+	Provider time.Duration `json:"provider,omitempty"`
+	MCP      time.Duration `json:"mcp,omitempty"`
+
+	// Tools overrides the backend defaults for matching tools. Keys are
+	// canonical tool IDs or path.Match patterns over them, such as
+	// "github:*" for a namespace. An exact ID wins over patterns, and a
+	// longer pattern over a shorter one. A zero value disables the timeout
+	// for matching tools.
+	Tools map[string]time.Duration `json:"tools,omitempty"`
+}
+
+// timeout returns the execution timeout for a call of tool through backend,
+// or zero for none.
+func (c TimeoutConfig) timeout(tool toolmodel.Tool, backend toolmodel.ToolBackend) time.Duration {
+	id := tool.ToolID()
+	if d, ok := c.Tools[id]; ok {
+		return d
+	}
+	best, found := "", false
+	for pattern := range c.Tools {
+		if ok, _ := path.Match(pattern, id); ok && (!found || len(pattern) > len(best) || len(pattern) == len(best) && pattern < best) {
+			best, found = pattern, true
+		}
+	}
+	if found {
+		return c.Tools[best]
+	}
+	switch backend.Kind {
+	case toolmodel.BackendKindLocal:
+		return c.Local
+	case toolmodel.BackendKindProvider:
+		return c.Provider
+	case toolmodel.BackendKindMCP:
+		return c.MCP
+	}
+	return 0
+}
+
+// callContext applies the execution timeout for a call of tool through
+// backend. It returns the timeout, or zero when none applies.
+func (r *DefaultRunner) callContext(ctx context.Context, tool toolmodel.Tool, backend toolmodel.ToolBackend) (context.Context, context.CancelFunc, time.Duration) {
+	d := r.cfg.Timeouts.timeout(tool, backend)
+	if d <= 0 {
+		return ctx, func() {}, 0
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, d, ErrCallTimeout)
+	return ctx, cancel, d
+}
+
+// callTimedOut reports whether callCtx was ended by the runner's execution
+// timeout rather than by its parent.
+func callTimedOut(callCtx context.Context) bool {
+	return callCtx.Err() != nil && errors.Is(context.Cause(callCtx), ErrCallTimeout)
+}
+
+// chainTimeoutKey is the context key for the chain timeout.
+type chainTimeoutKey struct{}
+
+// WithChainTimeout returns a context that bounds the total run time of
+// chains and graphs run with it. The timeout starts when the chain starts;
+// when it expires, the running step is canceled, the chain aborts regardless
+// of error policies, and the error matches ErrChainTimeout.
+func WithChainTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, chainTimeoutKey{}, d)
+}
+
+// chainContext applies a chain's deadline: the one recorded in cp, when the
+// run is checkpointed, or else that of the chain timeout attached to ctx.
+func chainContext(ctx context.Context, cp *Checkpoint) (context.Context, context.CancelFunc) {
+	deadline := chainDeadline(ctx)
+	if cp != nil && !cp.Deadline.IsZero() {
+		deadline = cp.Deadline
+	}
+	if deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadlineCause(ctx, deadline, ErrChainTimeout)
+}
+
+// chainDeadline returns the deadline of a chain starting now under the chain
+// timeout attached to ctx, or the zero time when there is none.
+func chainDeadline(ctx context.Context) time.Time {
+	d, _ := ctx.Value(chainTimeoutKey{}).(time.Duration)
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+// stepContext derives the context a step runs with, applying its Timeout.
+func stepContext(ctx context.Context, step ChainStep) (context.Context, context.CancelFunc) {
+	if step.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, time.Duration(step.Timeout), ErrStepTimeout)
+}
+
+// timeoutCause reports which runner deadline ended ctx: ErrStepTimeout,
+// ErrChainTimeout, or nil when ctx is live or was ended by the caller.
+func timeoutCause(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrStepTimeout) || errors.Is(cause, ErrChainTimeout) {
+		return cause
+	}
+	return nil
+}
+
+// timeoutError builds the ToolError for a step ended by a runner deadline.
+// The error matches both the cause and context.DeadlineExceeded.
+func timeoutError(step ChainStep, backend toolmodel.ToolBackend, cause error) error {
+	detail := cause
+	if errors.Is(cause, ErrStepTimeout) {
+		detail = fmt.Errorf("%w after %s", ErrStepTimeout, time.Duration(step.Timeout))
+	}
+	var b *toolmodel.ToolBackend
+	if backend.Kind != "" {
+		b = &backend
+	}
+	return WrapError(step.ToolID, b, "timeout", fmt.Errorf("%w: %w", detail, context.DeadlineExceeded))
+}
+
+// callTimeoutError builds the ToolError for a call ended by the runner's
+// execution timeout. The error matches both ErrCallTimeout and
+// context.DeadlineExceeded.
+func callTimeoutError(toolID string, backend toolmodel.ToolBackend, d time.Duration) error {
+	return WrapError(toolID, &backend, "timeout", fmt.Errorf("%w after %s: %w", ErrCallTimeout, d, context.DeadlineExceeded))
+}
@@ -0,0 +1,254 @@
+package toolrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonwraymond/toolmodel"
+)
+
+// sleepHandler blocks until ctx is done or d elapses.
+func sleepHandler(d time.Duration) LocalHandler {
+	return func(ctx context.Context, _ map[string]any) (any, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d):
+			return "slept", nil
+		}
+	}
+}
+
+func TestRunChain_StepTimeout(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+	})
+
+	start := time.Now()
+	_, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "slow", Timeout: Duration(20 * time.Millisecond)},
+	})
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("step timeout did not cancel the step")
+	}
+	if !errors.Is(err, ErrStepTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunChain() error = %v, want ErrStepTimeout and DeadlineExceeded", err)
+	}
+	if errors.Is(err, ErrChainTimeout) {
+		t.Error("step timeout should not match ErrChainTimeout")
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "timeout" {
+		t.Errorf("error = %v, want ToolError with op timeout", err)
+	}
+	if results[0].Policy != ErrorActionAbort {
+		t.Errorf("Policy = %q, want abort", results[0].Policy)
+	}
+}
+
+func TestRunChain_ChainTimeout(t *testing.T) {
+	var secondRan bool
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+		"next": func(_ context.Context, _ map[string]any) (any, error) {
+			secondRan = true
+			return nil, nil
+		},
+	})
+	runner.cfg.ChainErrorPolicy = ErrorPolicy{Action: ErrorActionContinue}
+
+	ctx := WithChainTimeout(context.Background(), 20*time.Millisecond)
+	_, _, err := runner.RunChain(ctx, []ChainStep{
+		{ToolID: "slow", Timeout: Duration(time.Minute)},
+		{ToolID: "next"},
+	})
+	if !errors.Is(err, ErrChainTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunChain() error = %v, want ErrChainTimeout and DeadlineExceeded", err)
+	}
+	if errors.Is(err, ErrStepTimeout) {
+		t.Error("chain timeout should not match ErrStepTimeout")
+	}
+	if secondRan {
+		t.Error("chain timeout must abort despite a continue policy")
+	}
+}
+
+func TestRunChain_OnTimeoutPolicy(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+		"fail": func(_ context.Context, _ map[string]any) (any, error) {
+			return nil, errTest
+		},
+		"cached": func(_ context.Context, args map[string]any) (any, error) {
+			return "cached", nil
+		},
+	})
+	policy := &ErrorPolicy{
+		Action:    ErrorActionAbort,
+		OnTimeout: &ErrorPolicy{Action: ErrorActionFallback, FallbackToolID: "cached"},
+	}
+
+	final, results, err := runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "slow", Timeout: Duration(10 * time.Millisecond), OnError: policy},
+	})
+	if err != nil {
+		t.Fatalf("RunChain() error = %v", err)
+	}
+	if results[0].Policy != ErrorActionFallback || final.Structured != "cached" {
+		t.Errorf("result = %+v, want fallback to cached", results[0])
+	}
+
+	// Non-timeout failures use the base action.
+	_, _, err = runner.RunChain(context.Background(), []ChainStep{
+		{ToolID: "fail", Timeout: Duration(time.Minute), OnError: policy},
+	})
+	if !errors.Is(err, ErrExecution) {
+		t.Errorf("RunChain() error = %v, want ErrExecution (abort)", err)
+	}
+}
+
+func TestRunGraph_StepTimeout(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+	})
+	_, err := runner.RunGraph(context.Background(), []GraphStep{
+		{ChainStep: ChainStep{ID: "a", ToolID: "slow", Timeout: Duration(10 * time.Millisecond)}},
+	}, 0)
+	if !errors.Is(err, ErrStepTimeout) {
+		t.Errorf("RunGraph() error = %v, want ErrStepTimeout", err)
+	}
+}
+
+func TestCheckErrorPolicy_NestedOnTimeout(t *testing.T) {
+	p := &ErrorPolicy{OnTimeout: &ErrorPolicy{OnTimeout: &ErrorPolicy{}}}
+	if err := checkErrorPolicy(p); !errors.Is(err, ErrInvalidChain) {
+		t.Errorf("checkErrorPolicy() error = %v, want ErrInvalidChain", err)
+	}
+	p = &ErrorPolicy{OnTimeout: &ErrorPolicy{Action: ErrorActionFallback}}
+	if err := checkErrorPolicy(p); !errors.Is(err, ErrInvalidChain) {
+		t.Errorf("checkErrorPolicy() error = %v, want ErrInvalidChain for missing fallback tool", err)
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	var step ChainStep
+	if err := json.Unmarshal([]byte(`{"toolId": "t", "timeout": "1m30s"}`), &step); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if time.Duration(step.Timeout) != 90*time.Second {
+		t.Errorf("Timeout = %v, want 1m30s", time.Duration(step.Timeout))
+	}
+	data, err := json.Marshal(step)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"toolId":"t","timeout":"1m30s"}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	for _, bad := range []string{`30`, `"soon"`, `"-1s"`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(bad), &d); err == nil {
+			t.Errorf("Unmarshal(%s) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestRun_CallTimeout(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+	})
+	runner.cfg.Timeouts = TimeoutConfig{Local: 20 * time.Millisecond}
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), "slow", nil)
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("call timeout did not cancel the call")
+	}
+	if !errors.Is(err, ErrCallTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want ErrCallTimeout and DeadlineExceeded", err)
+	}
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Op != "timeout" || toolErr.Backend == nil {
+		t.Errorf("error = %v, want ToolError with op timeout and backend", err)
+	}
+	if errors.Is(err, ErrExecution) {
+		t.Error("call timeout should not match ErrExecution")
+	}
+	if !DefaultRetryable(err) {
+		t.Error("DefaultRetryable() = false, want call timeouts retried")
+	}
+}
+
+func TestRun_CallerDeadlineIsNotCallTimeout(t *testing.T) {
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"slow": sleepHandler(time.Second),
+	})
+	runner.cfg.Timeouts = TimeoutConfig{Local: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := runner.Run(ctx, "slow", nil)
+	if err == nil || errors.Is(err, ErrCallTimeout) {
+		t.Fatalf("Run() error = %v, want a non-call-timeout error", err)
+	}
+}
+
+func TestRun_CallTimeoutRetried(t *testing.T) {
+	var calls int
+	runner := newLocalTestRunner(t, map[string]LocalHandler{
+		"flaky": func(ctx context.Context, _ map[string]any) (any, error) {
+			calls++
+			if calls == 1 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return "ok", nil
+		},
+	})
+	runner.cfg.Timeouts = TimeoutConfig{Local: 20 * time.Millisecond}
+	runner.cfg.RetryPolicy = RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	result, err := runner.Run(context.Background(), "flaky", nil)
+	if err != nil || result.Structured != "ok" || result.Attempts != 2 {
+		t.Errorf("Run() = %v (attempts %d), %v; want ok after 2 attempts", result.Structured, result.Attempts, err)
+	}
+}
+
+func TestTimeoutConfig_Timeout(t *testing.T) {
+	cfg := TimeoutConfig{
+		Local:    1 * time.Second,
+		Provider: 2 * time.Second,
+		MCP:      3 * time.Second,
+		Tools: map[string]time.Duration{
+			"github:*":           10 * time.Second,
+			"github:search*":     20 * time.Second,
+			"github:search_code": 30 * time.Second,
+			"github:delete":      0,
+		},
+	}
+	tests := []struct {
+		name    string
+		tool    toolmodel.Tool
+		backend toolmodel.ToolBackend
+		want    time.Duration
+	}{
+		{"local default", testTool("a"), testLocalBackend("a"), time.Second},
+		{"provider default", testTool("a"), testProviderBackend("p", "a"), 2 * time.Second},
+		{"mcp default", testTool("a"), testMCPBackend("srv"), 3 * time.Second},
+		{"namespace pattern", testToolWithNamespace("github", "list"), testMCPBackend("srv"), 10 * time.Second},
+		{"longer pattern", testToolWithNamespace("github", "search_issues"), testMCPBackend("srv"), 20 * time.Second},
+		{"exact id", testToolWithNamespace("github", "search_code"), testMCPBackend("srv"), 30 * time.Second},
+		{"zero disables", testToolWithNamespace("github", "delete"), testMCPBackend("srv"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.timeout(tt.tool, tt.backend); got != tt.want {
+				t.Errorf("timeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
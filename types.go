@@ -20,6 +20,17 @@ const (
 
 	// StreamEventError indicates an error occurred during streaming.
 	StreamEventError StreamEventKind = "error"
+
+	// StreamEventStepStarted indicates a chain step is about to run.
+	StreamEventStepStarted StreamEventKind = "step_started"
+
+	// StreamEventStepCompleted indicates a chain step completed or was
+	// skipped. Data holds the StepResult.
+	StreamEventStepCompleted StreamEventKind = "step_completed"
+
+	// StreamEventStepFailed indicates a chain step failed. Data holds the
+	// StepResult and Err the step error.
+	StreamEventStepFailed StreamEventKind = "step_failed"
 )
 
 // StreamEvent is a transport-agnostic streaming envelope.
@@ -37,7 +48,12 @@ type StreamEvent struct {
 	// For chunk events, this contains partial result data.
 	Data any `json:"data,omitempty"`
 
-	// Err is set when Kind is StreamEventError.
+	// StepIndex is the zero-based index of the chain step that produced the
+	// event. Only set by RunChainStream, and nil for events not produced by
+	// a step, so that step 0 is distinguishable from no step.
+	StepIndex *int `json:"stepIndex,omitempty"`
+
+	// Err is set when Kind is StreamEventError or StreamEventStepFailed.
 	// Not serialized to JSON - callers should extract error information
 	// from Data if needed for transmission.
 	Err error `json:"-"`
@@ -55,6 +71,10 @@ type ProgressEvent struct {
 // ChainStep defines one step in a sequential chain.
 // Chains execute steps in order, with optional data passing between steps.
 type ChainStep struct {
+	// ID optionally names the step. It is required for graph steps and is
+	// copied to the step's StepResult.
+	ID string `json:"id,omitempty"`
+
 	// ToolID is the canonical tool identifier to execute.
 	ToolID string `json:"toolId"`
 
@@ -63,24 +83,149 @@ type ChainStep struct {
 
 	// UsePrevious, when true, injects the previous step's structured result
 	// into args["previous"], overwriting any existing value.
+	// For graph steps, the injected value is a map of dependency ID to that
+	// dependency's structured result.
 	UsePrevious bool `json:"usePrevious,omitempty"`
+
+	// Project maps arg names to selections from the previous step's
+	// structured result, so that a step receives only the fields it needs
+	// instead of the whole value at args["previous"]. Selectors are paths
+	// relative to the previous value (for example "items[0].id") or JSON
+	// pointers (for example "/items/0/id"); "" or "$" selects the whole value.
+	// Projected args overwrite Args and apply with or without UsePrevious.
+	Project map[string]string `json:"project,omitempty"`
+
+	// When is an optional condition evaluated before the step runs, for
+	// example `previous.status == "needs_review"`. Paths may use the "steps",
+	// "previous", and "inputs" roots. When it is false the step is skipped
+	// and recorded with StepResult.Skipped; previous is left unchanged.
+	When string `json:"when,omitempty"`
+
+	// ForEach, when set, makes this a map step: a path expression (for
+	// example "previous.items") selecting an array from an earlier result.
+	// The tool is called once per element, with the element at
+	// args[ItemArg] and available to references as "item". The step's
+	// structured result is the array of outputs, in element order.
+	ForEach string `json:"forEach,omitempty"`
+
+	// ItemArg is the arg that receives each ForEach element.
+	// Defaults to "item".
+	ItemArg string `json:"itemArg,omitempty"`
+
+	// Concurrency bounds how many ForEach iterations run at once.
+	// Zero runs iterations one at a time.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Timeout bounds the step's run time, including retries and all
+	// ForEach iterations but not a fallback tool; zero means no step
+	// timeout. The step runs with a derived context, and an expired step
+	// fails with ErrStepTimeout.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// OnError overrides the chain's error policy for this step.
+	// Nil uses Config.ChainErrorPolicy.
+	OnError *ErrorPolicy `json:"onError,omitempty"`
+
+	// Compensate is an optional tool call that undoes this step's effects.
+	// If the chain aborts after this step succeeded, compensations run in
+	// reverse step order. Its args may reference this step and earlier ones.
+	Compensate *Compensation `json:"compensate,omitempty"`
+}
+
+// Compensation is a tool call that undoes the effects of a completed step,
+// for example cancelling a ticket that the step created.
+type Compensation struct {
+	// ToolID is the canonical identifier of the compensating tool.
+	ToolID string `json:"toolId"`
+
+	// Args are the arguments to pass to the compensating tool. String values
+	// may reference results as in ChainStep.Args.
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// ErrorAction selects how a chain reacts when a step fails.
+type ErrorAction string
+
+const (
+	// ErrorActionAbort stops the chain and returns the step error (the default).
+	ErrorActionAbort ErrorAction = "abort"
+
+	// ErrorActionContinue records the step error and continues with the next
+	// step. The failed step contributes a nil previous value.
+	ErrorActionContinue ErrorAction = "continue"
+
+	// ErrorActionFallback runs ErrorPolicy.FallbackToolID with the failed
+	// step's args plus args["error"] set to the error message. If the fallback
+	// succeeds its result stands in for the step; otherwise the chain aborts.
+	ErrorActionFallback ErrorAction = "fallback"
+)
+
+// ErrorPolicy describes how a chain handles a failed step.
+// The zero value aborts.
+type ErrorPolicy struct {
+	// Action is the policy to apply. Empty means ErrorActionAbort.
+	Action ErrorAction `json:"action,omitempty"`
+
+	// FallbackToolID is the tool to run when Action is ErrorActionFallback.
+	FallbackToolID string `json:"fallbackToolId,omitempty"`
+
+	// OnTimeout, when set, replaces this policy for steps that failed
+	// because their Timeout expired. It cannot itself set OnTimeout.
+	OnTimeout *ErrorPolicy `json:"onTimeout,omitempty"`
+}
+
+// GraphStep defines one node in a dependency graph of steps.
+// Steps without a dependency path between them may run concurrently.
+type GraphStep struct {
+	ChainStep
+
+	// DependsOn lists the IDs of steps that must succeed before this step runs.
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 // StepResult captures what happened at a single chain step.
 // It includes both the result and any error that occurred.
 type StepResult struct {
+	// ID is the step ID, when the step declared one.
+	ID string `json:"id,omitempty"`
+
 	// ToolID is the canonical tool identifier that was executed.
 	ToolID string `json:"toolId"`
 
 	// Backend is the backend that was used for execution.
 	Backend toolmodel.ToolBackend `json:"backend"`
 
+	// BackendsTried lists every backend the step's call was dispatched to,
+	// in order, including backends that failed before a failover.
+	BackendsTried []toolmodel.ToolBackend `json:"backendsTried,omitempty"`
+
 	// Result contains the execution result.
 	Result RunResult `json:"result"`
 
 	// Err is set if the step failed.
 	// Not serialized to JSON - callers should check this field explicitly.
 	Err error `json:"-"`
+
+	// Iterations records one result per element for ForEach steps,
+	// in element order.
+	Iterations []StepResult `json:"iterations,omitempty"`
+
+	// Skipped is true when the step's When condition was false and the
+	// step did not run.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Policy is the error action that fired for this step.
+	// Empty when the step succeeded.
+	Policy ErrorAction `json:"policy,omitempty"`
+
+	// Fallback records the fallback execution when Policy is
+	// ErrorActionFallback. On fallback success, Result holds the
+	// fallback's result.
+	Fallback *StepResult `json:"fallback,omitempty"`
+
+	// Compensation records the compensating call run for this step after
+	// the chain aborted. Nil when no compensation ran.
+	Compensation *StepResult `json:"compensation,omitempty"`
 }
 
 // RunResult is the normalized result of a tool execution.
@@ -101,4 +246,20 @@ type RunResult struct {
 	// MCPResult is the raw MCP CallToolResult when the backend was MCP.
 	// Nil for provider and local backends unless they return MCP-native results.
 	MCPResult *mcp.CallToolResult `json:"mcpResult,omitempty"`
+
+	// BackendsTried lists every backend the call was dispatched to, in
+	// order, including backends that failed before a failover.
+	BackendsTried []toolmodel.ToolBackend `json:"backendsTried,omitempty"`
+
+	// Attempts is the number of attempts Run made, including the first.
+	// It is greater than one only when a retry policy retried the call.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Steps holds the nested chain's step results when the tool is a
+	// CompositeTool. Nil for other tools.
+	Steps []StepResult `json:"steps,omitempty"`
+
+	// Coercions lists the changes coercion made to the args (see
+	// CoercionPolicy), in the order they were applied.
+	Coercions []Coercion `json:"coercions,omitempty"`
 }
@@ -2,6 +2,7 @@ package toolrun
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/jonwraymond/toolmodel"
@@ -75,6 +76,25 @@ func TestStreamEvent_ErrNotSerialized(t *testing.T) {
 	}
 }
 
+func TestStreamEvent_StepIndexJSON(t *testing.T) {
+	step := 0
+	data, err := json.Marshal(StreamEvent{Kind: StreamEventChunk, StepIndex: &step})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"stepIndex":0`) {
+		t.Errorf("step 0 event JSON = %s, want stepIndex 0", data)
+	}
+
+	data, err = json.Marshal(StreamEvent{Kind: StreamEventChunk})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "stepIndex") {
+		t.Errorf("non-chain event JSON = %s, want no stepIndex", data)
+	}
+}
+
 func TestChainStep_Defaults(t *testing.T) {
 	step := ChainStep{
 		ToolID: "test-tool",